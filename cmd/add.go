@@ -1,42 +1,113 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"time"
+	"strings"
 
+	"github.com/simondrake/copy-paste-notes/internal/editor"
 	"github.com/simondrake/copy-paste-notes/internal/notes"
-	"github.com/simondrake/copy-paste-notes/internal/sqlite"
 	"github.com/spf13/cobra"
 )
 
-func newAddCommand(client *sqlite.Client) *cobra.Command {
+func newAddCommand(openClient clientFunc) *cobra.Command {
 	var (
 		title       string
 		description string
+		tags        []string
+		edit        bool
+		fromFile    string
 	)
 
 	addCmd := &cobra.Command{
 		Use:   "add",
 		Short: "Adds a note",
-		Run: func(_ *cobra.Command, _ []string) {
-			_, err := client.InsertNote(notes.Note{
-				CreateTimestamp: time.Now().Format("2006-01-02 15:04:05"),
-				Title:           title,
-				Description:     description,
-			})
+		Long: `Adds a note.
+
+The description is taken from --description, the file given by --from-file or, if
+neither is given, from stdin when it is piped (e.g. "kubectl get pods -o yaml | cpn add -t pods").`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
 			if err != nil {
-				fmt.Fprintln(os.Stderr, "unable to insert note: ", err)
-				os.Exit(1)
+				return err
+			}
+
+			n := notes.Note{
+				Title:       title,
+				Description: description,
+				Tags:        tags,
+			}
+
+			if edit {
+				n, err = editor.Edit(n)
+				if errors.Is(err, editor.ErrCancelled) {
+					fmt.Fprintln(cmd.ErrOrStderr(), err)
+					return nil
+				}
+				if err != nil {
+					return fmt.Errorf("unable to edit note: %w", err)
+				}
+			} else {
+				if title == "" {
+					return errors.New("--title is required unless --edit is used")
+				}
+
+				if description == "" {
+					n.Description, err = readDescription(cmd.InOrStdin(), fromFile)
+					if err != nil {
+						return fmt.Errorf("unable to read description: %w", err)
+					}
+				}
 			}
+
+			_, err = client.Create(n)
+			if err != nil {
+				return fmt.Errorf("unable to insert note: %w", err)
+			}
+
+			return nil
 		},
 	}
 
 	addCmd.Flags().StringVarP(&title, "title", "t", "", "title of the note")
 	addCmd.Flags().StringVarP(&description, "description", "d", "", "description of the note")
+	addCmd.Flags().StringSliceVar(&tags, "tag", nil, "tag to add to the note (can be repeated)")
+	addCmd.Flags().BoolVarP(&edit, "edit", "e", false, "write the note in $VISUAL or $EDITOR, starting from any other flags given")
+	addCmd.Flags().StringVar(&fromFile, "from-file", "", "read the description of the note from a file")
 
-	addCmd.MarkFlagRequired("title")
-	addCmd.MarkFlagRequired("description")
+	addCmd.MarkFlagsMutuallyExclusive("description", "from-file")
+	addCmd.MarkFlagsMutuallyExclusive("edit", "from-file")
 
 	return addCmd
 }
+
+// readDescription reads a description from the given file or, if there isn't one, from stdin
+// when it isn't a terminal. Trailing newlines are removed.
+func readDescription(stdin io.Reader, file string) (string, error) {
+	var (
+		b   []byte
+		err error
+	)
+
+	switch {
+	case file != "":
+		b, err = os.ReadFile(file)
+	case !isTerminal(stdin):
+		b, err = io.ReadAll(stdin)
+	default:
+		return "", errors.New("--description, --from-file or piped input is required")
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	description := strings.TrimRight(string(b), "\r\n")
+	if strings.TrimSpace(description) == "" {
+		return "", errors.New("description must not be empty")
+	}
+
+	return description, nil
+}
@@ -0,0 +1,912 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/simondrake/copy-paste-notes/internal/clipboard"
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+	"github.com/simondrake/copy-paste-notes/internal/notes/notestest"
+	"github.com/simondrake/copy-paste-notes/internal/remote"
+	"github.com/simondrake/copy-paste-notes/internal/server"
+)
+
+// run executes cmd as a subcommand of a fresh root command with the given args and stdin,
+// returning what it wrote to stdout and stderr.
+func run(t *testing.T, cmd *cobra.Command, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+
+	root := &cobra.Command{Use: "cpn", SilenceErrors: true, SilenceUsage: true}
+	root.AddCommand(cmd)
+
+	var stdout, stderr bytes.Buffer
+
+	root.SetOut(&stdout)
+	root.SetErr(&stderr)
+	root.SetIn(strings.NewReader(stdin))
+	root.SetArgs(args)
+
+	err := root.Execute()
+
+	return stdout.String(), stderr.String(), err
+}
+
+// provide returns a function that provides v, in place of opening a store or clipboard.
+func provide[T any](v T) func() (T, error) {
+	return func() (T, error) { return v, nil }
+}
+
+// provideClipboard returns a function that provides cb, in place of creating a clipboard.
+func provideClipboard(cb clipboard.Clipboard) clipboardFunc {
+	return func(*cobra.Command) (clipboard.Clipboard, error) { return cb, nil }
+}
+
+func newTestClient(ns ...notes.Note) *notes.Client {
+	return notes.New(notestest.New(ns...))
+}
+
+func seed() []notes.Note {
+	return []notes.Note{
+		{Title: "pods", Description: "kubectl get pods -n {{namespace:default}}", Tags: []string{"k8s"}},
+		{Title: "logs", Description: "kubectl logs\\n-f", Tags: []string{"k8s", "debug"}},
+	}
+}
+
+func TestAdd(t *testing.T) {
+	t.Run("flags", func(t *testing.T) {
+		client := newTestClient()
+
+		_, _, err := run(t, newAddCommand(provide(client)), "", "add", "-t", "one", "-d", "first", "--tag", "a,b")
+		require.NoError(t, err)
+
+		n, err := client.GetByTitle("one")
+		require.NoError(t, err)
+		assert.Equal(t, "first", n.Description)
+		assert.Equal(t, []string{"a", "b"}, n.Tags)
+	})
+
+	t.Run("stdin", func(t *testing.T) {
+		client := newTestClient()
+
+		_, _, err := run(t, newAddCommand(provide(client)), "piped\n\n", "add", "-t", "one")
+		require.NoError(t, err)
+
+		n, err := client.GetByTitle("one")
+		require.NoError(t, err)
+		assert.Equal(t, "piped", n.Description)
+	})
+
+	t.Run("from file", func(t *testing.T) {
+		client := newTestClient()
+
+		file := filepath.Join(t.TempDir(), "description.txt")
+		require.NoError(t, os.WriteFile(file, []byte("from a file\n"), 0o600))
+
+		_, _, err := run(t, newAddCommand(provide(client)), "", "add", "-t", "one", "--from-file", file)
+		require.NoError(t, err)
+
+		n, err := client.GetByTitle("one")
+		require.NoError(t, err)
+		assert.Equal(t, "from a file", n.Description)
+	})
+
+	t.Run("missing title", func(t *testing.T) {
+		_, _, err := run(t, newAddCommand(provide(newTestClient())), "", "add", "-d", "first")
+		require.Error(t, err)
+	})
+
+	t.Run("duplicate title", func(t *testing.T) {
+		_, _, err := run(t, newAddCommand(provide(newTestClient(seed()...))), "", "add", "-t", "pods", "-d", "again")
+		require.ErrorIs(t, err, notes.ErrDuplicateTitle)
+	})
+}
+
+func TestGet(t *testing.T) {
+	client := newTestClient(seed()...)
+
+	t.Run("table", func(t *testing.T) {
+		out, _, err := run(t, newGetCommand(provide(client)), "", "get", "--id", "1")
+		require.NoError(t, err)
+		assert.Contains(t, out, "pods")
+		assert.Contains(t, out, "k8s")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		out, _, err := run(t, newGetCommand(provide(client)), "", "get", "-t", "pods", "-f", "json")
+		require.NoError(t, err)
+
+		var n notes.Note
+		require.NoError(t, json.Unmarshal([]byte(out), &n))
+		assert.Equal(t, 1, n.ID)
+		assert.Equal(t, []string{"namespace"}, n.Placeholders)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, _, err := run(t, newGetCommand(provide(client)), "", "get", "--id", "99")
+		require.ErrorIs(t, err, notes.ErrNotFound)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, _, err := run(t, newGetCommand(provide(client)), "", "get", "--id", "1", "-f", "xml")
+		require.ErrorIs(t, err, errUnsupportedFormat)
+	})
+}
+
+func TestList(t *testing.T) {
+	client := newTestClient(seed()...)
+
+	t.Run("table", func(t *testing.T) {
+		out, _, err := run(t, newListCommand(provide(client)), "", "list")
+		require.NoError(t, err)
+		assert.Contains(t, out, "pods")
+		assert.Contains(t, out, "logs")
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		out, _, err := run(t, newListCommand(provide(client)), "", "list", "-f", "json", "--tag", "k8s,debug", "--match", "all")
+		require.NoError(t, err)
+
+		var ns []notes.Note
+		require.NoError(t, json.Unmarshal([]byte(out), &ns))
+		require.Len(t, ns, 1)
+		assert.Equal(t, "logs", ns[0].Title)
+	})
+
+	t.Run("unsupported match", func(t *testing.T) {
+		_, _, err := run(t, newListCommand(provide(client)), "", "list", "--match", "some")
+		require.Error(t, err)
+	})
+
+	dated := newTestClient(
+		notes.Note{Title: "old", Description: "old", CreateTimestamp: time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)},
+		notes.Note{Title: "recent", Description: "recent", CreateTimestamp: time.Now().Add(-3 * 24 * time.Hour)},
+	)
+
+	listTitles := func(t *testing.T, args ...string) []string {
+		t.Helper()
+
+		out, _, err := run(t, newListCommand(provide(dated)), "", append([]string{"list", "-f", "json"}, args...)...)
+		require.NoError(t, err)
+
+		var ns []notes.Note
+		require.NoError(t, json.Unmarshal([]byte(out), &ns))
+
+		titles := make([]string, len(ns))
+		for i, n := range ns {
+			titles[i] = n.Title
+		}
+
+		return titles
+	}
+
+	t.Run("since and until", func(t *testing.T) {
+		assert.Equal(t, []string{"recent"}, listTitles(t, "--since", "1w"))
+		assert.Equal(t, []string{"old"}, listTitles(t, "--until", "1w"))
+		assert.Equal(t, []string{"old"}, listTitles(t, "--since", "2023-09-01T10:00:00Z", "--until", "2023-09-02"))
+		assert.Empty(t, listTitles(t, "--since", "2023-09-01T10:00:01Z", "--until", "2023-09-02"))
+	})
+
+	t.Run("invalid since", func(t *testing.T) {
+		_, _, err := run(t, newListCommand(provide(dated)), "", "list", "--since", "last week")
+		require.Error(t, err)
+	})
+
+	t.Run("times", func(t *testing.T) {
+		out, _, err := run(t, newListCommand(provide(dated)), "", "list")
+		require.NoError(t, err)
+		assert.Contains(t, out, "3d ago")
+
+		viper.Set("display.times", timesLocal)
+		t.Cleanup(func() { viper.Set("display.times", nil) })
+
+		out, _, err = run(t, newListCommand(provide(dated)), "", "list")
+		require.NoError(t, err)
+		assert.Contains(t, out, time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC).Local().Format(localTimeFormat))
+	})
+}
+
+func TestListSort(t *testing.T) {
+	client := newTestClient(seed()...)
+	require.NoError(t, client.MarkUsed(2))
+
+	listIDs := func(t *testing.T, args ...string) []int {
+		t.Helper()
+
+		out, _, err := run(t, newListCommand(provide(client)), "", append([]string{"list", "-f", "json"}, args...)...)
+		require.NoError(t, err)
+
+		var ns []notes.Note
+		require.NoError(t, json.Unmarshal([]byte(out), &ns))
+
+		ids := make([]int, len(ns))
+		for i, n := range ns {
+			ids[i] = n.ID
+		}
+
+		return ids
+	}
+
+	assert.Equal(t, []int{1, 2}, listIDs(t), "the notes are listed by ID by default")
+	assert.Equal(t, []int{2, 1}, listIDs(t, "--sort", "frecency"))
+	assert.Equal(t, []int{2, 1}, listIDs(t, "--sort", "uses"))
+	assert.Equal(t, []int{2, 1}, listIDs(t, "--sort", "title"))
+	assert.Equal(t, []int{1}, listIDs(t, "--limit", "1"))
+	assert.Equal(t, []int{2}, listIDs(t, "--sort", "frecency", "--limit", "1"))
+
+	_, _, err := run(t, newListCommand(provide(client)), "", "list", "--sort", "random")
+	require.ErrorIs(t, err, notes.ErrUnknownSortOrder)
+}
+
+func TestStats(t *testing.T) {
+	client := newTestClient(seed()...)
+	require.NoError(t, client.MarkUsed(2))
+	require.NoError(t, client.MarkUsed(2))
+	require.NoError(t, client.Delete(1))
+
+	out, _, err := run(t, newStatsCommand(provide(client)), "", "stats", "-f", "json")
+	require.NoError(t, err)
+
+	var s notes.Stats
+	require.NoError(t, json.Unmarshal([]byte(out), &s))
+	assert.Equal(t, 1, s.Notes)
+	assert.Equal(t, 1, s.Trashed)
+	assert.Equal(t, 2, s.Tags)
+	assert.Equal(t, 1, s.Used)
+	assert.Equal(t, 2, s.Uses)
+	require.Len(t, s.Top, 1)
+	assert.Equal(t, "logs", s.Top[0].Title)
+
+	out, _, err = run(t, newStatsCommand(provide(client)), "", "stats")
+	require.NoError(t, err)
+	assert.Contains(t, out, "logs")
+}
+
+func TestRelativeTime(t *testing.T) {
+	future := time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{d: -time.Hour, want: future.Local().Format(localTimeFormat)},
+		{d: 30 * time.Second, want: "just now"},
+		{d: 5 * time.Minute, want: "5m ago"},
+		{d: 3 * time.Hour, want: "3h ago"},
+		{d: 3 * 24 * time.Hour, want: "3d ago"},
+		{d: 15 * 24 * time.Hour, want: "2w ago"},
+		{d: 70 * 24 * time.Hour, want: "2mo ago"},
+		{d: 800 * 24 * time.Hour, want: "2y ago"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, relativeTime(tt.d, future))
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	client := newTestClient(seed()...)
+
+	out, _, err := run(t, newSearchCommand(provide(client)), "", "search", "-f", "json", "logs")
+	require.NoError(t, err)
+
+	var rs []notes.SearchResult
+	require.NoError(t, json.Unmarshal([]byte(out), &rs))
+	require.Len(t, rs, 1)
+	assert.Equal(t, "logs", rs[0].Title)
+
+	out, _, err = run(t, newSearchCommand(provide(client)), "", "search", "logs")
+	require.NoError(t, err)
+	assert.Contains(t, out, "kubectl logs")
+	assert.NotContains(t, out, "\033[")
+	assert.NotContains(t, out, notes.HighlightStart)
+}
+
+func TestCopy(t *testing.T) {
+	client := newTestClient(seed()...)
+
+	t.Run("render", func(t *testing.T) {
+		cb := &clipboard.Memory{}
+
+		_, _, err := run(t, newCopyCommand(provide(client), provideClipboard(cb)), "", "copy", "--id", "1", "--set", "namespace=kube-system")
+		require.NoError(t, err)
+		assert.Equal(t, "kubectl get pods -n kube-system", cb.Text())
+	})
+
+	t.Run("no render", func(t *testing.T) {
+		cb := &clipboard.Memory{}
+
+		_, _, err := run(t, newCopyCommand(provide(client), provideClipboard(cb)), "", "copy", "--title", "pods", "--no-render")
+		require.NoError(t, err)
+		assert.Equal(t, "kubectl get pods -n {{namespace:default}}", cb.Text())
+	})
+
+	t.Run("newlines", func(t *testing.T) {
+		cb := &clipboard.Memory{}
+
+		_, _, err := run(t, newCopyCommand(provide(client), provideClipboard(cb)), "", "copy", "--id", "2")
+		require.NoError(t, err)
+		assert.Equal(t, "kubectl logs\n-f", cb.Text())
+
+		_, _, err = run(t, newCopyCommand(provide(client), provideClipboard(cb)), "", "copy", "--id", "2", "--raw")
+		require.NoError(t, err)
+		assert.Equal(t, "kubectl logs\\n-f", cb.Text())
+	})
+
+	t.Run("pick", func(t *testing.T) {
+		cb := &clipboard.Memory{}
+
+		_, stderr, err := run(t, newCopyCommand(provide(client), provideClipboard(cb)), "2\n", "copy", "--no-render")
+		require.NoError(t, err)
+		assert.Contains(t, stderr, "  2) logs\n")
+		assert.Equal(t, "kubectl logs\n-f", cb.Text())
+	})
+
+	t.Run("stdout", func(t *testing.T) {
+		client := newTestClient(seed()...)
+
+		openClipboard := func(*cobra.Command) (clipboard.Clipboard, error) {
+			return nil, errors.New("no clipboard")
+		}
+
+		out, _, err := run(t, newCopyCommand(provide(client), openClipboard), "", "copy", "--title", "logs", "--stdout")
+		require.NoError(t, err)
+		assert.Equal(t, "kubectl logs\n-f", out)
+
+		out, _, err = run(t, newCopyCommand(provide(client), openClipboard), "", "copy", "--last", "--stdout", "--raw")
+		require.NoError(t, err)
+		assert.Equal(t, "kubectl logs\\n-f", out)
+
+		n, err := client.GetByID(2)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n.UseCount)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, _, err := run(t, newCopyCommand(provide(client), provideClipboard(&clipboard.Memory{})), "", "copy", "--id", "99")
+		require.ErrorIs(t, err, notes.ErrNotFound)
+	})
+
+	t.Run("last", func(t *testing.T) {
+		client := newTestClient(seed()...)
+
+		_, _, err := run(t, newCopyCommand(provide(client), provideClipboard(&clipboard.Memory{})), "", "copy", "--last")
+		require.ErrorIs(t, err, notes.ErrNotFound)
+
+		require.NoError(t, client.MarkUsed(2))
+
+		cb := &clipboard.Memory{}
+
+		_, _, err = run(t, newCopyCommand(provide(client), provideClipboard(cb)), "", "copy", "--last", "--raw")
+		require.NoError(t, err)
+		assert.Equal(t, "kubectl logs\\n-f", cb.Text())
+
+		n, err := client.GetByID(2)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n.UseCount)
+	})
+
+	t.Run("marks the note as used", func(t *testing.T) {
+		client := newTestClient(seed()...)
+
+		_, _, err := run(t, newCopyCommand(provide(client), provideClipboard(&clipboard.Memory{})), "", "copy", "--id", "2")
+		require.NoError(t, err)
+
+		n, err := client.GetByID(2)
+		require.NoError(t, err)
+		assert.False(t, n.LastUsedTimestamp.IsZero())
+
+		n, err = client.GetByID(1)
+		require.NoError(t, err)
+		assert.True(t, n.LastUsedTimestamp.IsZero())
+	})
+}
+
+func TestPaste(t *testing.T) {
+	client := newTestClient()
+	cb := &clipboard.Memory{}
+
+	_, _, err := run(t, newPasteCommand(provide(client), provideClipboard(cb)), "", "paste", "-t", "one")
+	require.Error(t, err, "the clipboard is empty")
+
+	require.NoError(t, cb.Copy("from the clipboard"))
+
+	_, _, err = run(t, newPasteCommand(provide(client), provideClipboard(cb)), "", "paste", "-t", "one")
+	require.NoError(t, err)
+
+	n, err := client.GetByTitle("one")
+	require.NoError(t, err)
+	assert.Equal(t, "from the clipboard", n.Description)
+}
+
+func TestRender(t *testing.T) {
+	client := newTestClient(seed()...)
+
+	out, _, err := run(t, newRenderCommand(provide(client)), "", "render", "--id", "1")
+	require.NoError(t, err)
+	assert.Equal(t, "kubectl get pods -n default\n", out)
+
+	out, _, err = run(t, newRenderCommand(provide(client)), "", "render", "-t", "pods", "--set", "namespace=web")
+	require.NoError(t, err)
+	assert.Equal(t, "kubectl get pods -n web\n", out)
+}
+
+func TestUpdate(t *testing.T) {
+	client := newTestClient(seed()...)
+
+	_, _, err := run(t, newUpdateCommand(provide(client)), "", "update", "--id", "1", "-d", "kubectl get pods -A", "--tag", "")
+	require.NoError(t, err)
+
+	n, err := client.GetByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, "pods", n.Title)
+	assert.Equal(t, "kubectl get pods -A", n.Description)
+	assert.Empty(t, n.Tags)
+
+	_, _, err = run(t, newUpdateCommand(provide(client)), "", "update", "--id", "99", "-t", "other")
+	require.ErrorIs(t, err, notes.ErrNotFound)
+
+	_, _, err = run(t, newUpdateCommand(provide(client)), "", "update", "--id", "1", "-t", " ")
+	require.ErrorIs(t, err, notes.ErrInvalidNote)
+}
+
+func TestEdit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sed as the editor")
+	}
+
+	t.Setenv("VISUAL", "sed -i.bak s/pods/services/g")
+
+	client := newTestClient(seed()...)
+
+	_, _, err := run(t, newEditCommand(provide(client)), "", "edit", "--id", "1")
+	require.NoError(t, err)
+
+	n, err := client.GetByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, "services", n.Title)
+	assert.Equal(t, "kubectl get services -n {{namespace:default}}", n.Description)
+}
+
+func TestDelete(t *testing.T) {
+	client := newTestClient(seed()...)
+
+	_, _, err := run(t, newDeleteCommand(provide(client)), "", "delete", "--id", "1")
+	require.NoError(t, err)
+
+	_, err = client.GetByID(1)
+	require.ErrorIs(t, err, notes.ErrNotFound)
+
+	trashed, err := client.Trash()
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+
+	_, _, err = run(t, newDeleteCommand(provide(client)), "", "delete", "--id", "2", "--permanent")
+	require.NoError(t, err)
+
+	trashed, err = client.Trash()
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+
+	_, _, err = run(t, newDeleteCommand(provide(client)), "", "delete", "--id", "99")
+	require.ErrorIs(t, err, notes.ErrNotFound)
+}
+
+func TestTag(t *testing.T) {
+	client := newTestClient(seed()...)
+
+	_, _, err := run(t, newTagCommand(provide(client)), "", "tag", "--id", "1", "pods", "k8s")
+	require.NoError(t, err)
+
+	_, _, err = run(t, newUntagCommand(provide(client)), "", "untag", "--id", "1", "k8s")
+	require.NoError(t, err)
+
+	n, err := client.GetByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pods"}, n.Tags)
+
+	_, _, err = run(t, newTagCommand(provide(client)), "", "tag", "--id", "1", "not,valid")
+	require.ErrorIs(t, err, notes.ErrInvalidNote)
+}
+
+func TestRevisions(t *testing.T) {
+	client := newTestClient(seed()...)
+
+	_, err := client.Update(1, notes.Note{Description: "kubectl get pods -A"})
+	require.NoError(t, err)
+
+	t.Run("history", func(t *testing.T) {
+		out, _, err := run(t, newHistoryCommand(provide(client)), "", "history", "--id", "1", "-f", "json")
+		require.NoError(t, err)
+
+		var rs []notes.Revision
+		require.NoError(t, json.Unmarshal([]byte(out), &rs))
+		require.Len(t, rs, 1)
+		assert.Equal(t, "kubectl get pods -n {{namespace:default}}", rs[0].Description)
+
+		_, _, err = run(t, newHistoryCommand(provide(client)), "", "history", "--id", "99")
+		require.ErrorIs(t, err, notes.ErrNotFound)
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		out, _, err := run(t, newDiffCommand(provide(client)), "", "diff", "--id", "1", "--rev", "1")
+		require.NoError(t, err)
+		assert.Contains(t, out, "-kubectl get pods -n {{namespace:default}}")
+		assert.Contains(t, out, "+kubectl get pods -A")
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		_, _, err := run(t, newRestoreCommand(provide(client)), "", "restore", "--id", "1", "--rev", "1")
+		require.NoError(t, err)
+
+		n, err := client.GetByID(1)
+		require.NoError(t, err)
+		assert.Equal(t, "kubectl get pods -n {{namespace:default}}", n.Description)
+
+		_, _, err = run(t, newRestoreCommand(provide(client)), "", "restore", "--id", "1", "--rev", "99")
+		require.ErrorIs(t, err, notes.ErrNotFound)
+	})
+}
+
+func TestTrash(t *testing.T) {
+	client := newTestClient(seed()...)
+
+	require.NoError(t, client.Delete(1))
+	require.NoError(t, client.Delete(2))
+
+	out, _, err := run(t, newTrashCommand(provide(client)), "", "trash", "list", "-f", "json")
+	require.NoError(t, err)
+
+	var ns []notes.Note
+	require.NoError(t, json.Unmarshal([]byte(out), &ns))
+	assert.Len(t, ns, 2)
+
+	_, _, err = run(t, newTrashCommand(provide(client)), "", "trash", "restore", "--id", "1")
+	require.NoError(t, err)
+
+	_, err = client.GetByID(1)
+	require.NoError(t, err)
+
+	t.Run("title reused", func(t *testing.T) {
+		_, _, err := run(t, newAddCommand(provide(client)), "", "add", "-t", "logs", "-d", "kubectl logs")
+		require.NoError(t, err)
+
+		_, _, err = run(t, newTrashCommand(provide(client)), "", "trash", "restore", "--id", "2")
+		require.ErrorIs(t, err, notes.ErrDuplicateTitle)
+		assert.EqualError(t, err, "unable to restore note: another note has the title of note 2, rename it before restoring this one")
+	})
+
+	_, _, err = run(t, newTrashCommand(provide(client)), "", "trash", "empty")
+	require.NoError(t, err)
+
+	trashed, err := client.Trash()
+	require.NoError(t, err)
+	assert.Empty(t, trashed)
+
+	_, _, err = run(t, newTrashCommand(provide(client)), "", "trash", "empty", "--older-than", "soon")
+	require.Error(t, err)
+}
+
+func TestExportImport(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "notes.yaml")
+
+	_, _, err := run(t, newExportCommand(provide(newTestClient(seed()...))), "", "export", "-o", file)
+	require.NoError(t, err)
+
+	client := newTestClient(notes.Note{Title: "pods", Description: "existing"})
+
+	out, _, err := run(t, newImportCommand(provide(client)), "", "import", file, "--on-conflict", "rename")
+	require.NoError(t, err)
+	assert.Contains(t, out, "1 renamed")
+
+	ns, err := client.List()
+	require.NoError(t, err)
+	assert.Len(t, ns, 3)
+
+	t.Run("stdin", func(t *testing.T) {
+		client := newTestClient()
+
+		_, _, err := run(t, newImportCommand(provide(client)), `[{"title": "one", "description": "first"}]`, "import", "-")
+		require.NoError(t, err)
+
+		_, err = client.GetByTitle("one")
+		require.NoError(t, err)
+	})
+
+	t.Run("unknown conflict policy", func(t *testing.T) {
+		_, _, err := run(t, newImportCommand(provide(client)), "", "import", file, "--on-conflict", "merge")
+		require.ErrorIs(t, err, notes.ErrUnknownConflictPolicy)
+	})
+}
+
+func TestSyncDir(t *testing.T) {
+	client := newTestClient(seed()...)
+	dir := t.TempDir()
+
+	out, _, err := run(t, newSyncCommand(provide(client)), "", "sync", "dir", dir)
+	require.NoError(t, err)
+	assert.NotEmpty(t, out)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+}
+
+func TestMigrate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cpn.db")
+
+	out, _, err := run(t, newMigrateCommand(provide(file)), "", "migrate", "version")
+	require.NoError(t, err)
+	assert.Equal(t, "no migrations have been applied\n", out)
+
+	_, _, err = run(t, newMigrateCommand(provide(file)), "", "migrate", "up")
+	require.NoError(t, err)
+
+	out, _, err = run(t, newMigrateCommand(provide(file)), "", "migrate", "up")
+	require.NoError(t, err)
+	assert.Equal(t, "no change\n", out)
+
+	_, _, err = run(t, newMigrateCommand(provide(file)), "", "migrate", "down", "-1")
+	require.Error(t, err)
+}
+
+func TestServe(t *testing.T) {
+	t.Run("needs a token", func(t *testing.T) {
+		_, _, err := run(t, newServeCommand(provide(newTestClient())), "", "serve", "--addr", "127.0.0.1:0")
+		require.Error(t, err)
+	})
+
+	t.Run("shuts down when cancelled", func(t *testing.T) {
+		viper.Set("serve.token", "secret")
+		t.Cleanup(func() { viper.Set("serve.token", nil) })
+
+		var stderr bytes.Buffer
+
+		cmd := newServeCommand(provide(newTestClient()))
+		cmd.SetArgs([]string{"--addr", "127.0.0.1:0"})
+		cmd.SetErr(&stderr)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		require.NoError(t, cmd.ExecuteContext(ctx))
+		assert.Contains(t, stderr.String(), "serving notes on http://127.0.0.1:")
+	})
+}
+
+func TestRPC(t *testing.T) {
+	stdin := `{"jsonrpc": "2.0", "id": 1, "method": "render", "params": {"title": "pods", "values": {"namespace": "dev"}}}
+{"jsonrpc": "2.0", "id": 2, "method": "get", "params": {"id": 99}}
+`
+
+	out, _, err := run(t, newRPCCommand(provide(newTestClient(seed()...))), stdin, "rpc")
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"jsonrpc": "2.0", "id": 1, "result": "kubectl get pods -n dev"}`, lines[0])
+	assert.JSONEq(t, `{"jsonrpc": "2.0", "id": 2, "error": {"code": -32001, "message": "no note with id 99"}}`, lines[1])
+}
+
+func TestCompletion(t *testing.T) {
+	home := t.TempDir()
+	db := filepath.Join(home, "cpn.db")
+	require.NoError(t, os.WriteFile(db, nil, 0o600))
+
+	t.Setenv("HOME", home)
+	t.Setenv("CPN_DB_DRIVER", "")
+	t.Setenv("CPN_DB_FILE", db)
+
+	client := newTestClient(append(seed(), notes.Note{Title: "long", Description: strings.Repeat("word ", 20)})...)
+
+	complete := func(t *testing.T, cmd *cobra.Command, args ...string) []string {
+		t.Helper()
+
+		out, _, err := run(t, cmd, "", append([]string{cobra.ShellCompRequestCmd}, args...)...)
+		require.NoError(t, err)
+
+		return strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	}
+
+	t.Run("titles", func(t *testing.T) {
+		assert.Equal(t, []string{
+			"pods\tkubectl get pods -n {{namespace:default}}",
+			"logs\tkubectl logs -f",
+			"long\t" + strings.Repeat("word ", 11) + "word…",
+			":4",
+		}, complete(t, newGetCommand(provide(client)), "get", "--title", ""))
+
+		assert.Equal(t, []string{"pods\tkubectl get pods -n {{namespace:default}}", ":4"}, complete(t, newCopyCommand(provide(client), provideClipboard(nil)), "copy", "--title", "p"))
+	})
+
+	t.Run("ids", func(t *testing.T) {
+		assert.Equal(t, []string{"1\tpods", "2\tlogs", "3\tlong", ":4"}, complete(t, newDeleteCommand(provide(client)), "delete", "--id", ""))
+		assert.Equal(t, []string{"2\tlogs", ":4"}, complete(t, newUpdateCommand(provide(client)), "update", "--id", "2"))
+	})
+
+	t.Run("missing store", func(t *testing.T) {
+		t.Setenv("CPN_DB_FILE", filepath.Join(home, "missing.db"))
+
+		assert.Equal(t, []string{":4"}, complete(t, newGetCommand(provide(client)), "get", "--title", ""))
+	})
+}
+
+func TestShellInit(t *testing.T) {
+	for shell, binding := range map[string]string{
+		"bash": `bind -m emacs-standard -x '"\C-x\C-n": __cpn_insert_note'`,
+		"zsh":  "bindkey -M emacs '^X^N' __cpn_insert_note",
+		"fish": `bind \cx\cn __cpn_insert_note`,
+	} {
+		t.Run(shell, func(t *testing.T) {
+			out, _, err := run(t, newShellInitCommand(), "", "shell-init", shell)
+			require.NoError(t, err)
+			assert.Contains(t, out, "cpn copy --stdout </dev/tty")
+			assert.Contains(t, out, binding)
+		})
+	}
+
+	t.Run("unsupported shell", func(t *testing.T) {
+		_, _, err := run(t, newShellInitCommand(), "", "shell-init", "ksh")
+		require.Error(t, err)
+
+		_, _, err = run(t, newShellInitCommand(), "", "shell-init")
+		require.Error(t, err)
+	})
+}
+
+func TestVersion(t *testing.T) {
+	out, _, err := run(t, versionCmd, "", "version")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out, "Copy Paste Notes"))
+}
+
+func TestRoot(t *testing.T) {
+	home := t.TempDir()
+
+	t.Setenv("HOME", home)
+	t.Setenv("CPN_DB_DRIVER", "")
+	t.Setenv("CPN_DB_FILE", "")
+
+	// The same buffer is used each time, as the completion command keeps the writer it's
+	// first run with
+	var stdout bytes.Buffer
+
+	execute := func(args ...string) (string, error) {
+		stdout.Reset()
+
+		rootCmd.SetOut(&stdout)
+		rootCmd.SetArgs(args)
+
+		t.Cleanup(func() {
+			rootCmd.SetOut(nil)
+			rootCmd.SetArgs(nil)
+		})
+
+		err := rootCmd.Execute()
+		require.NoError(t, deps.Close())
+
+		return stdout.String(), err
+	}
+
+	t.Run("version doesn't open the store", func(t *testing.T) {
+		_, err := execute("version")
+		require.NoError(t, err)
+		assert.NoFileExists(t, filepath.Join(home, "cpn.db"))
+	})
+
+	t.Run("completion doesn't create the store", func(t *testing.T) {
+		out, err := execute(cobra.ShellCompRequestCmd, "get", "--title", "")
+		require.NoError(t, err)
+		assert.Equal(t, ":4\n", out)
+		assert.NoFileExists(t, filepath.Join(home, "cpn.db"))
+	})
+
+	t.Run("completion scripts", func(t *testing.T) {
+		for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+			out, err := execute("completion", shell)
+			require.NoError(t, err, shell)
+			assert.Contains(t, out, "copy-paste-notes", shell)
+		}
+	})
+
+	configured := filepath.Join(home, "configured.json")
+	config := filepath.Join(home, "config.yaml")
+	require.NoError(t, os.WriteFile(config, []byte("db:\n  driver: file\n  file: "+configured+"\n"), 0o600))
+
+	t.Run("config", func(t *testing.T) {
+		_, err := execute("--config", config, "add", "-t", "one", "-d", "first")
+		require.NoError(t, err)
+		assert.FileExists(t, configured)
+	})
+
+	t.Run("db overrides the config", func(t *testing.T) {
+		file := filepath.Join(home, "flag.json")
+
+		_, err := execute("--config", config, "--db", file, "add", "-t", "two", "-d", "second")
+		require.NoError(t, err)
+
+		out, err := execute("--config", config, "--db", file, "list", "-f", "json")
+		require.NoError(t, err)
+
+		var ns []notes.Note
+		require.NoError(t, json.Unmarshal([]byte(out), &ns))
+		require.Len(t, ns, 1)
+		assert.Equal(t, "two", ns[0].Title)
+	})
+
+	t.Run("completion uses the config and flags", func(t *testing.T) {
+		out, err := execute(cobra.ShellCompRequestCmd, "--config", config, "--db", filepath.Join(home, "flag.json"), "get", "--title", "t")
+		require.NoError(t, err)
+		assert.Equal(t, "two\tsecond\n:4\n", out)
+	})
+
+	t.Run("file clipboard writes to the output", func(t *testing.T) {
+		t.Setenv("CPN_CLIPBOARD_BACKEND", "file")
+		t.Setenv("CPN_CLIPBOARD_FILE", "-")
+
+		out, err := execute("--config", config, "--db", filepath.Join(home, "flag.json"), "copy", "--title", "two")
+		require.NoError(t, err)
+		assert.Equal(t, "second", out)
+	})
+
+	t.Run("migrate needs the sqlite driver", func(t *testing.T) {
+		_, err := execute("--config", config, "migrate", "version")
+		require.Error(t, err)
+	})
+
+	t.Run("remote driver", func(t *testing.T) {
+		srv := httptest.NewServer(server.New(newTestClient(seed()...), "secret", nil))
+		t.Cleanup(srv.Close)
+
+		t.Setenv("CPN_DB_DRIVER", "remote")
+		t.Setenv("CPN_DB_URL", srv.URL)
+		t.Setenv("CPN_DB_TOKEN", "secret")
+
+		out, err := execute("list", "-f", "json", "--sort", "title")
+		require.NoError(t, err)
+
+		var ns []notes.Note
+		require.NoError(t, json.Unmarshal([]byte(out), &ns))
+		require.Len(t, ns, 2)
+		assert.Equal(t, "logs", ns[0].Title)
+
+		t.Setenv("CPN_DB_TOKEN", "wrong")
+
+		_, err = execute("list")
+		require.ErrorIs(t, err, remote.ErrUnauthorized)
+
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		t.Cleanup(slow.Close)
+
+		t.Setenv("CPN_DB_URL", slow.URL)
+		t.Setenv("CPN_DB_TIMEOUT", "10ms")
+
+		_, err = execute("list")
+		require.ErrorIs(t, err, remote.ErrUnreachable)
+	})
+
+	t.Run("unsupported times", func(t *testing.T) {
+		t.Cleanup(func() { _ = rootCmd.PersistentFlags().Set("times", "") })
+
+		_, err := execute("--times", "soon", "version")
+		require.Error(t, err)
+	})
+}
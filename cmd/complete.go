@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+// maxCompletionDescription is the longest description shown next to a completion, in runes.
+const maxCompletionDescription = 60
+
+type completionFunc func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective)
+
+// isCompletion returns true if cmd generates a completion script or completes a command line,
+// neither of which should fail because of the config.
+func isCompletion(cmd *cobra.Command) bool {
+	switch {
+	case cmd.Name() == cobra.ShellCompRequestCmd, cmd.Name() == cobra.ShellCompNoDescRequestCmd:
+		return true
+	case cmd.HasParent() && cmd.Parent().Name() == "completion":
+		return true
+	default:
+		return false
+	}
+}
+
+// completeIDs completes the IDs of the notes, described by their titles.
+func completeIDs(openClient clientFunc) completionFunc {
+	return completeNotes(openClient, func(n notes.Note) (string, string) {
+		return strconv.Itoa(n.ID), n.Title
+	})
+}
+
+// completeTitles completes the titles of the notes, described by their descriptions.
+func completeTitles(openClient clientFunc) completionFunc {
+	return completeNotes(openClient, func(n notes.Note) (string, string) {
+		return n.Title, completionDescription(n.Description)
+	})
+}
+
+// completeNotes completes a value of each note that starts with what's been typed. Nothing is
+// completed if the notes can't be listed, e.g. because the database doesn't exist yet, as
+// there's nowhere to report the error.
+func completeNotes(openClient clientFunc, value func(notes.Note) (string, string)) completionFunc {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		// The root command's PersistentPreRunE doesn't load the config when completing, as the
+		// flags of the command being completed, e.g. --db, haven't been parsed by then
+		if err := initConfig(); err != nil || !storeExists() {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		client, err := openClient()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		ns, err := client.List()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		var completions []string
+
+		for _, n := range ns {
+			v, description := value(n)
+			if !strings.HasPrefix(v, toComplete) {
+				continue
+			}
+
+			completions = append(completions, v+"\t"+description)
+		}
+
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// storeExists returns false if the configured driver stores the notes in a file that doesn't
+// exist, which opening the store would create.
+func storeExists() bool {
+	switch viper.GetString("db.driver") {
+	case driverSQLite, driverFile:
+		_, err := os.Stat(viper.GetString("db.file"))
+		return !errors.Is(err, fs.ErrNotExist)
+	default:
+		return true
+	}
+}
+
+// completionDescription collapses a note's description onto one line, shortening it if it's
+// too long to show next to a completion.
+func completionDescription(description string) string {
+	description = strings.Join(strings.Fields(notes.ParseNewlines(description)), " ")
+
+	if r := []rune(description); len(r) > maxCompletionDescription {
+		description = string(r[:maxCompletionDescription-1]) + "…"
+	}
+
+	return description
+}
@@ -2,85 +2,143 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
-	"strings"
-	"time"
-
-	"golang.design/x/clipboard"
 
 	"github.com/spf13/cobra"
 
+	"github.com/simondrake/copy-paste-notes/internal/clipboard"
 	"github.com/simondrake/copy-paste-notes/internal/notes"
-	"github.com/simondrake/copy-paste-notes/internal/sqlite"
+	"github.com/simondrake/copy-paste-notes/internal/picker"
 )
 
-func newCopyCommand(client *sqlite.Client) *cobra.Command {
+func newCopyCommand(openClient clientFunc, openClipboard clipboardFunc) *cobra.Command {
 	var (
-		id    int
-		title string
-		raw   bool
+		id       int
+		title    string
+		raw      bool
+		noRender bool
+		set      []string
+		last     bool
+		stdout   bool
 	)
 
 	addCmd := &cobra.Command{
 		Use:   "copy",
 		Short: "Copies a note into the system clipboard",
-		Run: func(_ *cobra.Command, _ []string) {
+		Long: `Copies a note into the system clipboard.
+
+If neither --id, --title nor --last is given, an interactive fuzzy finder is opened to choose
+the note.
+
+Placeholders in the note, written as {{name}} or <name> with an optional default value
+(e.g. {{port:8080}}), are filled in from --set or by prompting for them.
+
+With --stdout the note is written to stdout rather than copied, e.g. for the widgets printed by
+shell-init.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			// The clipboard is created first, so that any problem with it is reported before
+			// the note is picked
+			var cb clipboard.Clipboard
+
+			if !stdout {
+				cb, err = openClipboard(cmd)
+				if err != nil {
+					return err
+				}
+			}
+
 			var note *notes.Note
 
-			if id != 0 {
-				var err error
-				note, err = client.GetNoteByID(id)
+			if last {
+				note, err = client.LastUsed()
 				if err != nil {
-					fmt.Fprintln(os.Stderr, "unable to get note: ", err)
-					os.Exit(1)
+					return fmt.Errorf("unable to get note: %w", err)
 				}
 			} else {
-				var err error
-				note, err = client.GetNoteByTitle(title)
+				note, err = selectNote(cmd, client, id, title)
 				if err != nil {
-					fmt.Fprintln(os.Stderr, "unable to get note: ", err)
-					os.Exit(1)
+					return err
 				}
 			}
 
-			if err := clipboard.Init(); err != nil {
-				fmt.Fprintln(os.Stderr, "unable to initialise clipboard: ", err)
-				os.Exit(1)
-			}
-
 			if !raw {
-				spl := strings.Split(note.Description, "\\n")
+				note.Description = notes.ParseNewlines(note.Description)
+			}
 
-				out := make([]string, len(spl))
-				for i, s := range spl {
-					out[i] = strings.TrimSpace(s)
+			if !noRender {
+				note.Description, err = renderPlaceholders(note.Description, set, cmd.InOrStdin(), cmd.ErrOrStderr())
+				if err != nil {
+					return err
 				}
-
-				note.Description = strings.Join(out, "\n")
 			}
 
-			if os.Getenv("WAYLAND_DISPLAY") != "" {
-				if err := exec.Command("wl-copy", note.Description).Run(); err != nil {
-					fmt.Fprintln(os.Stderr, "unable to copy with wl-clipboard: ", err)
-					os.Exit(1)
-				}
+			if stdout {
+				fmt.Fprint(cmd.OutOrStdout(), note.Description)
+			} else if err := cb.Copy(note.Description); err != nil {
+				return fmt.Errorf("unable to copy note: %w", err)
+			}
 
-				return
+			if err := client.MarkUsed(note.ID); err != nil {
+				return fmt.Errorf("unable to record note use: %w", err)
 			}
 
-			clipboard.Write(clipboard.FmtText, []byte(note.Description))
-			// TODO - for some reason this is needed on linux. Find a way to avoid this cruft.
-			time.Sleep(500 * time.Millisecond)
+			return nil
 		},
 	}
 
 	addCmd.Flags().IntVar(&id, "id", 0, "id of the note")
 	addCmd.Flags().StringVar(&title, "title", "", "title of the note")
 	addCmd.Flags().BoolVarP(&raw, "raw", "r", false, "Whether to copy the raw text (e.g. don't parse the newline character as a literal newline)")
+	addCmd.Flags().BoolVar(&noRender, "no-render", false, "copy placeholders as they are, rather than filling them in")
+	addCmd.Flags().StringArrayVar(&set, "set", nil, "value for a placeholder, as name=value (can be repeated)")
 
-	addCmd.MarkFlagsOneRequired("id", "title")
-	addCmd.MarkFlagsMutuallyExclusive("id", "title")
+	addCmd.Flags().BoolVar(&last, "last", false, "copy the most recently copied note again")
+	addCmd.Flags().BoolVar(&stdout, "stdout", false, "write the note to stdout instead of the clipboard")
+
+	addCmd.MarkFlagsMutuallyExclusive("id", "title", "last")
+	addCmd.MarkFlagsMutuallyExclusive("no-render", "set")
+
+	addCmd.RegisterFlagCompletionFunc("id", completeIDs(openClient))
+	addCmd.RegisterFlagCompletionFunc("title", completeTitles(openClient))
 
 	return addCmd
 }
+
+// selectNote gets the note by its ID or title or, if neither is given, by asking the user to
+// pick one using the command's input and output.
+func selectNote(cmd *cobra.Command, client *notes.Client, id int, title string) (*notes.Note, error) {
+	var note *notes.Note
+
+	switch {
+	case id != 0:
+		var err error
+		note, err = client.GetByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get note: %w", err)
+		}
+	case title != "":
+		var err error
+		note, err = client.GetByTitle(title)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get note: %w", err)
+		}
+	default:
+		ns, err := client.List()
+		if err != nil {
+			return nil, fmt.Errorf("unable to list notes: %w", err)
+		}
+
+		note, err = picker.Pick(ns, func(n notes.Note) string {
+			return notes.ParseNewlines(n.Description)
+		}, cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr())
+		if err != nil {
+			return nil, fmt.Errorf("unable to pick note: %w", err)
+		}
+	}
+
+	return note, nil
+}
@@ -2,29 +2,44 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
-	"github.com/simondrake/copy-paste-notes/internal/sqlite"
 	"github.com/spf13/cobra"
 )
 
-func newDeleteCommand(client *sqlite.Client) *cobra.Command {
-	var id int
+func newDeleteCommand(openClient clientFunc) *cobra.Command {
+	var (
+		id        int
+		permanent bool
+	)
 
 	addCmd := &cobra.Command{
 		Use:   "delete",
-		Short: "Deletes a note by it's ID",
-		Run: func(_ *cobra.Command, _ []string) {
-			if err := client.DeleteNote(id); err != nil {
-				fmt.Fprintln(os.Stderr, "unable to delete note: ", err)
-				os.Exit(1)
+		Short: "Deletes a note by it's ID, moving it to the trash",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
 			}
+
+			del := client.Delete
+			if permanent {
+				del = client.Purge
+			}
+
+			if err := del(id); err != nil {
+				return fmt.Errorf("unable to delete note: %w", err)
+			}
+
+			return nil
 		},
 	}
 
 	addCmd.Flags().IntVar(&id, "id", 0, "id of the note")
+	addCmd.Flags().BoolVar(&permanent, "permanent", false, "delete the note permanently, rather than moving it to the trash")
 
 	addCmd.MarkFlagRequired("id")
 
+	addCmd.RegisterFlagCompletionFunc("id", completeIDs(openClient))
+
 	return addCmd
 }
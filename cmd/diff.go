@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+func newDiffCommand(openClient clientFunc) *cobra.Command {
+	var (
+		id       int
+		revision int
+		raw      bool
+	)
+
+	diffCmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Shows the changes to a note's description since a revision",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			r, err := client.Revision(id, revision)
+			if err != nil {
+				return fmt.Errorf("unable to get revision: %w", err)
+			}
+
+			n, err := client.GetByID(id)
+			if err != nil {
+				return fmt.Errorf("unable to get note: %w", err)
+			}
+
+			before, after := r.Description, n.Description
+			if !raw {
+				before, after = notes.ParseNewlines(before), notes.ParseNewlines(after)
+			}
+
+			diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+				A:        difflib.SplitLines(before),
+				B:        difflib.SplitLines(after),
+				FromFile: fmt.Sprintf("revision %d", r.Revision),
+				FromDate: r.CreateTimestamp.Local().Format(localTimeFormat),
+				ToFile:   "current",
+				Context:  3,
+			})
+			if err != nil {
+				return fmt.Errorf("unable to diff note: %w", err)
+			}
+
+			fmt.Fprint(cmd.OutOrStdout(), diff)
+
+			return nil
+		},
+	}
+
+	diffCmd.Flags().IntVar(&id, "id", 0, "id of the note")
+	diffCmd.Flags().IntVar(&revision, "rev", 0, "revision of the note to compare against")
+	diffCmd.Flags().BoolVarP(&raw, "raw", "r", false, "Whether to diff the raw text (e.g. don't parse the newline character as a literal newline)")
+
+	diffCmd.MarkFlagRequired("id")
+	diffCmd.MarkFlagRequired("rev")
+
+	diffCmd.RegisterFlagCompletionFunc("id", completeIDs(openClient))
+
+	return diffCmd
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/simondrake/copy-paste-notes/internal/editor"
+)
+
+func newEditCommand(openClient clientFunc) *cobra.Command {
+	var id int
+
+	editCmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Edits a note in $VISUAL or $EDITOR",
+		Long: `Edits a note in $VISUAL or $EDITOR.
+
+The note is opened as a file with a front matter header holding the title and tags,
+followed by the description. Saving the file empty or unchanged cancels the edit.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			n, err := client.GetByID(id)
+			if err != nil {
+				return fmt.Errorf("unable to get note: %w", err)
+			}
+
+			edited, err := editor.Edit(*n)
+			if errors.Is(err, editor.ErrCancelled) {
+				fmt.Fprintln(cmd.ErrOrStderr(), err)
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("unable to edit note: %w", err)
+			}
+
+			if _, err := client.Update(id, edited); err != nil {
+				return fmt.Errorf("unable to update note: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	editCmd.Flags().IntVar(&id, "id", 0, "id of the note")
+
+	editCmd.MarkFlagRequired("id")
+
+	editCmd.RegisterFlagCompletionFunc("id", completeIDs(openClient))
+
+	return editCmd
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/simondrake/copy-paste-notes/internal/transfer"
+)
+
+func newExportCommand(openClient clientFunc) *cobra.Command {
+	var (
+		format string
+		output string
+	)
+
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Exports all notes, including their timestamps and tags",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			f, err := transfer.ParseFormat(format, output)
+			if err != nil {
+				return fmt.Errorf("unable to export notes: %w", err)
+			}
+
+			ns, err := client.List()
+			if err != nil {
+				return fmt.Errorf("unable to list notes: %w", err)
+			}
+
+			sort.Slice(ns, func(i, j int) bool { return ns[i].ID < ns[j].ID })
+
+			w := cmd.OutOrStdout()
+
+			if output != "" && output != "-" {
+				file, err := os.Create(output)
+				if err != nil {
+					return fmt.Errorf("unable to create output file: %w", err)
+				}
+
+				defer file.Close()
+
+				w = file
+			}
+
+			if err := transfer.Encode(w, f, ns); err != nil {
+				return fmt.Errorf("unable to export notes: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	exportCmd.Flags().StringVarP(&format, "format", "f", "", "export format to use, defaults to the output file extension or json [json, ndjson, yaml, csv]")
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "file to write the notes to (default is stdout)")
+
+	return exportCmd
+}
@@ -1,17 +1,16 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/simondrake/copy-paste-notes/internal/notes"
-	"github.com/simondrake/copy-paste-notes/internal/sqlite"
+	"github.com/simondrake/copy-paste-notes/internal/placeholder"
 	"github.com/spf13/cobra"
 )
 
-func newGetCommand(client *sqlite.Client) *cobra.Command {
+func newGetCommand(openClient clientFunc) *cobra.Command {
 	var (
 		id     int
 		title  string
@@ -21,44 +20,52 @@ func newGetCommand(client *sqlite.Client) *cobra.Command {
 	addCmd := &cobra.Command{
 		Use:   "get",
 		Short: "Gets a note in JSON format",
-		Run: func(_ *cobra.Command, _ []string) {
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
 			var n *notes.Note
 
 			if title != "" {
-				var err error
-				n, err = client.GetNoteByTitle(title)
+				n, err = client.GetByTitle(title)
 				if err != nil {
-					fmt.Fprintln(os.Stderr, "unable to get note: ", err)
-					os.Exit(1)
+					return fmt.Errorf("unable to get note: %w", err)
 				}
 			} else {
 				// If title isn't defined then id must be
-				var err error
-				n, err = client.GetNoteByID(id)
+				n, err = client.GetByID(id)
 				if err != nil {
-					fmt.Fprintln(os.Stderr, "unable to get note: ", err)
-					os.Exit(1)
+					return fmt.Errorf("unable to get note: %w", err)
 				}
 			}
 
 			switch format {
 			case "table":
-				table := tablewriter.NewWriter(os.Stdout)
-				table.SetHeader([]string{"ID", "Create Timestamp", "Title", "Description"})
-				table.Append([]string{fmt.Sprint(n.ID), n.CreateTimestamp, n.Title, n.Description})
+				table := tablewriter.NewWriter(cmd.OutOrStdout())
+				table.SetHeader([]string{"ID", "Created", "Updated", "Last Used", "Uses", "Title", "Tags", "Description"})
+				table.Append([]string{
+					fmt.Sprint(n.ID),
+					formatTime(n.CreateTimestamp),
+					formatTime(n.UpdateTimestamp),
+					formatTime(n.LastUsedTimestamp),
+					fmt.Sprint(n.UseCount),
+					n.Title,
+					strings.Join(n.Tags, ", "),
+					n.Description,
+				})
 
 				table.Render()
 			case "json":
-				e := json.NewEncoder(os.Stdout)
-				e.SetIndent("", "    ")
-				if err := e.Encode(n); err != nil {
-					fmt.Fprintln(os.Stderr, "Unable to encode json response: ", err)
-					os.Exit(1)
-				}
+				n.Placeholders = placeholder.Names(n.Description)
+
+				return writeJSON(cmd.OutOrStdout(), n)
 			default:
-				fmt.Fprintln(os.Stderr, "unsupported format option")
-				os.Exit(1)
+				return errUnsupportedFormat
 			}
+
+			return nil
 		},
 	}
 
@@ -69,5 +76,8 @@ func newGetCommand(client *sqlite.Client) *cobra.Command {
 	addCmd.MarkFlagsOneRequired("id", "title")
 	addCmd.MarkFlagsMutuallyExclusive("id", "title")
 
+	addCmd.RegisterFlagCompletionFunc("id", completeIDs(openClient))
+	addCmd.RegisterFlagCompletionFunc("title", completeTitles(openClient))
+
 	return addCmd
 }
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func newHistoryCommand(openClient clientFunc) *cobra.Command {
+	var (
+		id     int
+		format string
+	)
+
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "Lists the previous revisions of a note",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			// Make sure the note exists, rather than showing an empty history
+			rs, err := client.Revisions(id)
+			if err != nil {
+				return fmt.Errorf("unable to list revisions: %w", err)
+			}
+
+			switch format {
+			case "table":
+				table := tablewriter.NewWriter(cmd.OutOrStdout())
+				table.SetHeader([]string{"Revision", "Timestamp", "Title", "Description"})
+
+				for _, r := range rs {
+					table.Append([]string{fmt.Sprint(r.Revision), formatTime(r.CreateTimestamp), r.Title, r.Description})
+				}
+
+				table.Render()
+			case "json":
+				return writeJSON(cmd.OutOrStdout(), rs)
+			default:
+				return errUnsupportedFormat
+			}
+
+			return nil
+		},
+	}
+
+	historyCmd.Flags().IntVar(&id, "id", 0, "id of the note")
+	historyCmd.Flags().StringVarP(&format, "format", "f", "table", "output format to use [table, json]")
+
+	historyCmd.MarkFlagRequired("id")
+
+	historyCmd.RegisterFlagCompletionFunc("id", completeIDs(openClient))
+
+	return historyCmd
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+	"github.com/simondrake/copy-paste-notes/internal/transfer"
+)
+
+func newImportCommand(openClient clientFunc) *cobra.Command {
+	var (
+		format     string
+		onConflict string
+	)
+
+	importCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Imports notes from a file created by export, use - to read from stdin",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			f, err := transfer.ParseFormat(format, args[0])
+			if err != nil {
+				return fmt.Errorf("unable to import notes: %w", err)
+			}
+
+			r := cmd.InOrStdin()
+
+			if args[0] != "-" {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return fmt.Errorf("unable to open file: %w", err)
+				}
+
+				defer file.Close()
+
+				r = file
+			}
+
+			ns, err := transfer.Decode(r, f)
+			if err != nil {
+				return fmt.Errorf("unable to read notes: %w", err)
+			}
+
+			res, err := client.Import(ns, notes.ConflictPolicy(onConflict))
+			if err != nil {
+				return fmt.Errorf("unable to import notes: %w", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "imported %d notes (%d inserted, %d overwritten, %d renamed, %d skipped)\n",
+				res.Inserted+res.Overwritten+res.Renamed, res.Inserted, res.Overwritten, res.Renamed, res.Skipped)
+
+			return nil
+		},
+	}
+
+	importCmd.Flags().StringVarP(&format, "format", "f", "", "import format to use, defaults to the file extension or json [json, ndjson, yaml, csv]")
+	importCmd.Flags().StringVar(&onConflict, "on-conflict", string(notes.ConflictSkip), "what to do when a note with the same title exists [skip, overwrite, rename]")
+
+	return importCmd
+}
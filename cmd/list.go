@@ -1,50 +1,80 @@
 package cmd
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"strings"
+	"time"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 
 	"github.com/simondrake/copy-paste-notes/internal/notes"
-	"github.com/simondrake/copy-paste-notes/internal/sqlite"
 )
 
-func newListCommand(client *sqlite.Client) *cobra.Command {
+func newListCommand(openClient clientFunc) *cobra.Command {
 	var (
 		autoWrapText bool
 		raw          bool
 		titleOnly    bool
 		format       string
+		tags         []string
+		match        string
+		since        string
+		until        string
+		sortBy       string
+		limit        int
 	)
 
 	listCmd := &cobra.Command{
 		Use:   "list",
 		Short: "Lists all notes",
-		Run: func(_ *cobra.Command, _ []string) {
-			ns, err := client.ListNotes()
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
 			if err != nil {
-				fmt.Fprintln(os.Stderr, "unable to list notes: ", err)
-				os.Exit(1)
+				return err
+			}
+
+			if match != "any" && match != "all" {
+				return errors.New("unsupported match option")
+			}
+
+			from, err := parseTime(since)
+			if err != nil {
+				return fmt.Errorf("invalid --since value: %w", err)
+			}
+
+			to, err := parseTime(until)
+			if err != nil {
+				return fmt.Errorf("invalid --until value: %w", err)
+			}
+
+			ns, err := client.ListByTags(tags, match == "all")
+			if err != nil {
+				return fmt.Errorf("unable to list notes: %w", err)
+			}
+
+			ns = notes.FilterByCreated(ns, from, to)
+
+			if err := notes.Sort(ns, notes.SortOrder(sortBy), time.Now()); err != nil {
+				return err
+			}
+
+			if limit > 0 && len(ns) > limit {
+				ns = ns[:limit]
 			}
 
 			switch format {
 			case "table":
-				outputTable(ns, titleOnly, autoWrapText, raw)
+				outputTable(cmd.OutOrStdout(), ns, titleOnly, autoWrapText, raw)
 			case "json":
-				e := json.NewEncoder(os.Stdout)
-				e.SetIndent("", "    ")
-				if err := e.Encode(ns); err != nil {
-					fmt.Fprintln(os.Stderr, "Unable to encode json response: ", err)
-					os.Exit(1)
-				}
+				return writeJSON(cmd.OutOrStdout(), ns)
 			default:
-				fmt.Fprintln(os.Stderr, "unsupported format option")
-				os.Exit(1)
+				return errUnsupportedFormat
 			}
+
+			return nil
 		},
 	}
 
@@ -52,39 +82,61 @@ func newListCommand(client *sqlite.Client) *cobra.Command {
 	listCmd.Flags().BoolVarP(&raw, "raw", "r", false, "Whether to show the raw text (e.g. don't parse the newline character as a literal newline)")
 	listCmd.Flags().BoolVar(&titleOnly, "title-only", true, "Whether to only show the title")
 	listCmd.Flags().StringVarP(&format, "format", "f", "table", "output format to use [table, json]")
+	listCmd.Flags().StringSliceVar(&tags, "tag", nil, "only list notes with this tag (can be repeated)")
+	listCmd.Flags().StringVar(&match, "match", "any", "whether notes must have any or all of the given tags [any, all]")
+	listCmd.Flags().StringVar(&since, "since", "", "only list notes created at or after this time, given as an age (e.g. 7d, 2w, 12h), a date (2006-01-02) or an RFC3339 timestamp")
+	listCmd.Flags().StringVar(&until, "until", "", "only list notes created before this time, in the same forms as --since")
+	listCmd.Flags().StringVar(&sortBy, "sort", string(notes.SortID), "order to list the notes in [id, frecency, recent, created, title, uses]")
+	listCmd.Flags().IntVar(&limit, "limit", 0, "maximum number of notes to list, or 0 for all of them")
 
 	return listCmd
 }
 
-func outputTable(ns []notes.Note, titleOnly bool, autoWrapText bool, raw bool) {
-	table := tablewriter.NewWriter(os.Stdout)
+// parseTime parses a point in time given as an age relative to now (e.g. 7d), a date in the
+// user's time zone or an RFC3339 timestamp. An empty string is returned as the zero time.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	age, err := parseAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected an age (e.g. 7d), a date (2006-01-02) or an RFC3339 timestamp, got %q", s)
+	}
+
+	return time.Now().Add(-age), nil
+}
+
+func outputTable(w io.Writer, ns []notes.Note, titleOnly bool, autoWrapText bool, raw bool) {
+	table := tablewriter.NewWriter(w)
 
 	if titleOnly {
-		table.SetHeader([]string{"ID", "Create Timestamp", "Title"})
+		table.SetHeader([]string{"ID", "Created", "Updated", "Title", "Tags"})
 	} else {
-		table.SetHeader([]string{"ID", "Create Timestamp", "Title", "Description"})
+		table.SetHeader([]string{"ID", "Created", "Updated", "Title", "Tags", "Description"})
 	}
 
 	table.SetAutoWrapText(autoWrapText)
 
 	for _, n := range ns {
 		if titleOnly {
-			table.Append([]string{fmt.Sprint(n.ID), n.CreateTimestamp, n.Title})
+			table.Append([]string{fmt.Sprint(n.ID), formatTime(n.CreateTimestamp), formatTime(n.UpdateTimestamp), n.Title, strings.Join(n.Tags, ", ")})
 			continue
 		}
 
 		if !raw {
-			spl := strings.Split(n.Description, "\\n")
-
-			out := make([]string, len(spl))
-			for i, s := range spl {
-				out[i] = strings.TrimSpace(s)
-			}
-
-			n.Description = strings.Join(out, "\n")
+			n.Description = notes.ParseNewlines(n.Description)
 		}
 
-		table.Append([]string{fmt.Sprint(n.ID), n.CreateTimestamp, n.Title, n.Description})
+		table.Append([]string{fmt.Sprint(n.ID), formatTime(n.CreateTimestamp), formatTime(n.UpdateTimestamp), n.Title, strings.Join(n.Tags, ", "), n.Description})
 	}
 
 	table.Render()
@@ -1,68 +1,168 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"time"
+	"io"
+	"strconv"
 
-	"github.com/cenkalti/backoff/v4"
 	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/spf13/cobra"
+
+	"github.com/simondrake/copy-paste-notes/internal/sqlite"
 )
 
-func newMigrateCommand(file string) *cobra.Command {
+func newMigrateCommand(sqliteFile func() (string, error)) *cobra.Command {
 	migrateCmd := &cobra.Command{
 		Use:   "migrate",
-		Short: "Runs the DB migration scripts",
-		Run: func(_ *cobra.Command, _ []string) {
-			uri := fmt.Sprintf("sqlite3://%s", file)
+		Short: "Manages the DB schema migrations",
+		Long: `Manages the DB schema migrations.
 
-			var m *migrate.Migrate
+Pending migrations are applied automatically whenever the database is opened, so these
+commands are only needed to roll back or repair the schema. Running migrate without a
+subcommand is the same as running "migrate up".`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withMigrate(cmd.OutOrStdout(), sqliteFile, func(m *migrate.Migrate) error {
+				return m.Up()
+			})
+		},
+	}
 
-			if err := retry(func() error {
-				var err error
+	migrateCmd.AddCommand(&cobra.Command{
+		Use:   "up",
+		Short: "Applies all pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withMigrate(cmd.OutOrStdout(), sqliteFile, func(m *migrate.Migrate) error {
+				return m.Up()
+			})
+		},
+	})
 
-				fmt.Println("trying migration...")
+	var all bool
 
-				m, err = migrate.New("file://internal/migrations", uri)
+	downCmd := &cobra.Command{
+		Use:   "down [N]",
+		Short: "Rolls back the last N migrations (default 1)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n := 1
+			if len(args) == 1 {
+				v, err := parseMigrateArg(args[0])
 				if err != nil {
-					fmt.Printf("migration failed: (%+v) \n", err)
 					return err
 				}
 
-				fmt.Println("migration completed successfully")
+				n = v
+			}
 
-				return nil
-			}); err != nil {
-				fmt.Fprintln(os.Stderr, "error creating new migration: ", err)
-				os.Exit(1)
+			return withMigrate(cmd.OutOrStdout(), sqliteFile, func(m *migrate.Migrate) error {
+				if all {
+					return m.Down()
+				}
+
+				return m.Steps(-n)
+			})
+		},
+	}
+
+	downCmd.Flags().BoolVar(&all, "all", false, "roll back all migrations, deleting all notes")
+
+	migrateCmd.AddCommand(downCmd)
+
+	migrateCmd.AddCommand(&cobra.Command{
+		Use:   "goto V",
+		Short: "Migrates up or down to version V",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := parseMigrateArg(args[0])
+			if err != nil {
+				return err
 			}
 
-			if err := m.Up(); err != nil {
-				fmt.Fprintln(os.Stderr, "error running migration: ", err)
-				os.Exit(1)
+			return withMigrate(cmd.OutOrStdout(), sqliteFile, func(m *migrate.Migrate) error {
+				return m.Migrate(uint(v))
+			})
+		},
+	})
+
+	migrateCmd.AddCommand(&cobra.Command{
+		Use:   "version",
+		Short: "Prints the current migration version",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return withMigrate(cmd.OutOrStdout(), sqliteFile, func(m *migrate.Migrate) error {
+				v, dirty, err := m.Version()
+				if errors.Is(err, migrate.ErrNilVersion) {
+					fmt.Fprintln(cmd.OutOrStdout(), "no migrations have been applied")
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+
+				if dirty {
+					fmt.Fprintf(cmd.OutOrStdout(), "%d (dirty)\n", v)
+					return nil
+				}
+
+				fmt.Fprintln(cmd.OutOrStdout(), v)
+
+				return nil
+			})
+		},
+	})
+
+	migrateCmd.AddCommand(&cobra.Command{
+		Use:   "force V",
+		Short: "Sets the migration version to V and clears the dirty flag, without running any migrations",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, err := parseMigrateArg(args[0])
+			if err != nil {
+				return err
 			}
+
+			return withMigrate(cmd.OutOrStdout(), sqliteFile, func(m *migrate.Migrate) error {
+				return m.Force(v)
+			})
 		},
-	}
+	})
 
 	return migrateCmd
 }
 
-// Retry is an exponential backoff retry helper. It is used to wait for postgres to boot up
-func retry(op func() error) error {
-	bo := backoff.NewExponentialBackOff()
-	bo.MaxInterval = time.Second * 10
-	bo.MaxElapsedTime = time.Minute * 5
+// withMigrate runs op against the migrations for the database in the sqlite file.
+func withMigrate(out io.Writer, sqliteFile func() (string, error), op func(*migrate.Migrate) error) error {
+	file, err := sqliteFile()
+	if err != nil {
+		return err
+	}
+
+	m, err := sqlite.NewMigrate(file)
+	if err != nil {
+		return fmt.Errorf("error creating new migration: %w", err)
+	}
+
+	defer m.Close()
 
-	if err := backoff.Retry(op, bo); err != nil {
-		if bo.NextBackOff() == backoff.Stop {
-			return fmt.Errorf("reached retry deadline")
+	if err := op(m); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			fmt.Fprintln(out, "no change")
+			return nil
 		}
 
-		return fmt.Errorf("retry failed: %w", err)
+		return fmt.Errorf("error running migration: %w", err)
 	}
 
 	return nil
 }
+
+func parseMigrateArg(arg string) (int, error) {
+	v, err := strconv.Atoi(arg)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid argument %q, expected a positive number", arg)
+	}
+
+	return v, nil
+}
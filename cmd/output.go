@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+var errUnsupportedFormat = errors.New("unsupported format option")
+
+// The ways timestamps can be shown in table output, set with the display.times config key.
+const (
+	timesRelative = "relative"
+	timesLocal    = "local"
+)
+
+// localTimeFormat is the layout timestamps are shown in, in the user's time zone.
+const localTimeFormat = "2006-01-02 15:04:05"
+
+// formatTime formats a timestamp for table output, either relative to now (e.g. "3d ago") or
+// in the user's time zone, depending on display.times. Zero timestamps are shown as blank.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	if viper.GetString("display.times") == timesLocal {
+		return t.Local().Format(localTimeFormat)
+	}
+
+	return relativeTime(time.Now().Sub(t), t)
+}
+
+// relativeTime describes how long ago something happened, falling back to the local time for
+// anything in the future.
+func relativeTime(d time.Duration, t time.Time) string {
+	const day = 24 * time.Hour
+
+	switch {
+	case d < -time.Minute:
+		return t.Local().Format(localTimeFormat)
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", d/time.Minute)
+	case d < day:
+		return fmt.Sprintf("%dh ago", d/time.Hour)
+	case d < 7*day:
+		return fmt.Sprintf("%dd ago", d/day)
+	case d < 30*day:
+		return fmt.Sprintf("%dw ago", d/(7*day))
+	case d < 365*day:
+		return fmt.Sprintf("%dmo ago", d/(30*day))
+	default:
+		return fmt.Sprintf("%dy ago", d/(365*day))
+	}
+}
+
+// writeJSON writes v to w as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "    ")
+
+	if err := e.Encode(v); err != nil {
+		return fmt.Errorf("unable to encode json response: %w", err)
+	}
+
+	return nil
+}
+
+// isTerminal reports whether the reader or writer is a terminal, which is never the case for
+// the buffers used in tests.
+func isTerminal(v any) bool {
+	f, ok := v.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
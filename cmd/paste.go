@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+func newPasteCommand(openClient clientFunc, openClipboard clipboardFunc) *cobra.Command {
+	var (
+		title string
+		tags  []string
+	)
+
+	pasteCmd := &cobra.Command{
+		Use:   "paste",
+		Short: "Adds a note from the contents of the system clipboard",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			cb, err := openClipboard(cmd)
+			if err != nil {
+				return err
+			}
+
+			description, err := cb.Paste()
+			if err != nil {
+				return fmt.Errorf("unable to read clipboard: %w", err)
+			}
+
+			if strings.TrimSpace(description) == "" {
+				return errors.New("the clipboard is empty")
+			}
+
+			_, err = client.Create(notes.Note{
+				Title:       title,
+				Description: description,
+				Tags:        tags,
+			})
+			if err != nil {
+				return fmt.Errorf("unable to insert note: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	pasteCmd.Flags().StringVarP(&title, "title", "t", "", "title of the note")
+	pasteCmd.Flags().StringSliceVar(&tags, "tag", nil, "tag to add to the note (can be repeated)")
+
+	pasteCmd.MarkFlagRequired("title")
+
+	return pasteCmd
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+	"github.com/simondrake/copy-paste-notes/internal/placeholder"
+)
+
+func newRenderCommand(openClient clientFunc) *cobra.Command {
+	var (
+		id    int
+		title string
+		raw   bool
+		set   []string
+	)
+
+	renderCmd := &cobra.Command{
+		Use:   "render",
+		Short: "Fills in the placeholders of a note and prints the result",
+		Long: `Fills in the placeholders of a note and prints the result.
+
+Placeholders are written as {{name}} or <name>, with an optional default value
+(e.g. {{port:8080}}). Their values are taken from --set, or prompted for when
+stdin is a terminal. If neither --id nor --title is given, an interactive fuzzy
+finder is opened to choose the note.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			note, err := selectNote(cmd, client, id, title)
+			if err != nil {
+				return err
+			}
+
+			if !raw {
+				note.Description = notes.ParseNewlines(note.Description)
+			}
+
+			out, err := renderPlaceholders(note.Description, set, cmd.InOrStdin(), cmd.ErrOrStderr())
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), out)
+
+			return nil
+		},
+	}
+
+	renderCmd.Flags().IntVar(&id, "id", 0, "id of the note")
+	renderCmd.Flags().StringVarP(&title, "title", "t", "", "title of the note")
+	renderCmd.Flags().BoolVarP(&raw, "raw", "r", false, "Whether to render the raw text (e.g. don't parse the newline character as a literal newline)")
+	renderCmd.Flags().StringArrayVar(&set, "set", nil, "value for a placeholder, as name=value (can be repeated)")
+
+	renderCmd.MarkFlagsMutuallyExclusive("id", "title")
+
+	renderCmd.RegisterFlagCompletionFunc("id", completeIDs(openClient))
+	renderCmd.RegisterFlagCompletionFunc("title", completeTitles(openClient))
+
+	return renderCmd
+}
+
+// renderPlaceholders fills in the placeholders in the description using the name=value pairs
+// in set, prompting for any others on errOut when in is a terminal.
+func renderPlaceholders(description string, set []string, in io.Reader, errOut io.Writer) (string, error) {
+	values, err := placeholder.ParseValues(set)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse --set: %w", err)
+	}
+
+	if isTerminal(in) {
+		values, err = placeholder.Prompt(placeholder.Parse(description), values, in, errOut)
+		if err != nil {
+			return "", fmt.Errorf("unable to read placeholder values: %w", err)
+		}
+	}
+
+	out, err := placeholder.Render(description, values)
+	if err != nil {
+		return "", fmt.Errorf("unable to render note (use --set name=value): %w", err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newRestoreCommand(openClient clientFunc) *cobra.Command {
+	var (
+		id       int
+		revision int
+	)
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restores a note to a previous revision",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			if _, err := client.Restore(id, revision); err != nil {
+				return fmt.Errorf("unable to restore note: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	restoreCmd.Flags().IntVar(&id, "id", 0, "id of the note")
+	restoreCmd.Flags().IntVar(&revision, "rev", 0, "revision of the note to restore")
+
+	restoreCmd.MarkFlagRequired("id")
+	restoreCmd.MarkFlagRequired("rev")
+
+	restoreCmd.RegisterFlagCompletionFunc("id", completeIDs(openClient))
+
+	return restoreCmd
+}
@@ -1,13 +1,20 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/simondrake/copy-paste-notes/internal/clipboard"
+	"github.com/simondrake/copy-paste-notes/internal/filestore"
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+	"github.com/simondrake/copy-paste-notes/internal/remote"
 	"github.com/simondrake/copy-paste-notes/internal/sqlite"
 )
 
@@ -18,10 +25,32 @@ var rootCmd = &cobra.Command{
 	Short: "copy-paste-notes is a command-line note taking app",
 	Long: `Manage all your notes using the command-line, including the ability to
 	copy directly into your system clipboard.`,
+	// Errors are printed by Execute, and shouldn't be followed by the usage
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	// The config is loaded once the flags have been parsed, so that --config and --db are
+	// honoured. The store is only opened by the commands that need it. Completion loads the
+	// config itself, as it's needed.
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		if isCompletion(cmd) {
+			return nil
+		}
+
+		return initConfig()
+	},
 }
 
+// deps is shared by the commands, opening the store and the clipboard the first time they're
+// needed.
+var deps = &dependencies{}
+
 func Execute() {
 	err := rootCmd.Execute()
+
+	if cerr := deps.Close(); cerr != nil && err == nil {
+		err = fmt.Errorf("unable to close store: %w", cerr)
+	}
+
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -29,94 +58,200 @@ func Execute() {
 }
 
 func init() {
-	initConfig()
-
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.copy-paste-notes.yaml)")
+	rootCmd.PersistentFlags().String("db", "", "database file, overriding db.file (default is $HOME/cpn.db, or $HOME/cpn.json with the file driver)")
 
-	if err := setupCommands(); err != nil {
-		fmt.Fprintln(os.Stderr, "unable to setup commands: ", err)
-		os.Exit(1)
-	}
+	rootCmd.PersistentFlags().String("times", "", "how to show timestamps in tables, overriding display.times [relative, local] (default is relative)")
+
+	// Binding can only fail if the flag is nil
+	_ = viper.BindPFlag("db.file", rootCmd.PersistentFlags().Lookup("db"))
+	_ = viper.BindPFlag("display.times", rootCmd.PersistentFlags().Lookup("times"))
+
+	setupCommands()
 }
 
-func initConfig() {
+func initConfig() error {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Unable to determine home directory:", err)
-		os.Exit(1)
+		return fmt.Errorf("unable to determine home directory: %w", err)
+	}
+
+	// Environment Variables
+	for key, env := range map[string]string{
+		"db.driver":         "CPN_DB_DRIVER",
+		"db.file":           "CPN_DB_FILE",
+		"db.url":            "CPN_DB_URL",
+		"db.token":          "CPN_DB_TOKEN",
+		"db.timeout":        "CPN_DB_TIMEOUT",
+		"clipboard.backend": "CPN_CLIPBOARD_BACKEND",
+		"clipboard.file":    "CPN_CLIPBOARD_FILE",
+		"display.times":     "CPN_DISPLAY_TIMES",
+		"serve.token":       "CPN_SERVE_TOKEN",
+	} {
+		if err := viper.BindEnv(key, env); err != nil {
+			return fmt.Errorf("unable to bind viper key to environment variable: %w", err)
+		}
 	}
 
 	if cfgFile != "" {
-		// Use config file from the flag.
+		// Use config file from the flag, which must exist
 		viper.SetConfigFile(cfgFile)
+
+		if err := viper.ReadInConfig(); err != nil {
+			return fmt.Errorf("unable to read in config: %w", err)
+		}
 	} else {
-		viper.AddConfigPath(home)
-		viper.SetConfigType("yaml")
-		viper.SetConfigName(".copy-paste-notes")
-
-		// Custom cofig file mapped as a volume when using Docker
-		viper.AddConfigPath("/config")
-		viper.SetConfigType("yaml")
-		viper.SetConfigName("config")
-	}
+		// Custom config file mapped as a volume when using Docker, with the config file in the
+		// home directory merged over it. Missing files are ignored as we use
+		// defaults/environment variables and if anything required isn't set properly (e.g. db
+		// file) we'll error later on
+		for _, file := range []string{"/config/config.yaml", path.Join(home, ".copy-paste-notes.yaml")} {
+			viper.SetConfigFile(file)
 
-	// Environment Variables
-	handleBindEnvErr(viper.BindEnv("db.file", "CPN_DB_FILE"))
-
-	// Merge config
-	if err := viper.MergeInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// Config file not found; ignore as we use defaults/environment variables
-			// and if anything required isn't set properly (e.g. db file) we'll error later on
-		} else {
-			// Config file was found but another error was produced
-			fmt.Fprintln(os.Stderr, "unable to merge in config: ", err)
-			os.Exit(1)
+			if err := viper.MergeInConfig(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("unable to merge in config: %w", err)
+			}
 		}
 	}
 
+	viper.SetDefault("db.driver", driverSQLite)
 	viper.SetDefault("db.file", path.Join(home, "cpn.db"))
+	viper.SetDefault("clipboard.backend", clipboard.BackendAuto)
+	viper.SetDefault("display.times", timesRelative)
 
-	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			// Config file not found; ignore as we use defaults/environment variables
-			// and if anything required isn't set properly (e.g. db file) we'll error later on
-		} else {
-			// Config file was found but another error was produced
-			fmt.Fprintln(os.Stderr, "unable to read in config: ", err)
-			os.Exit(1)
-		}
+	if times := viper.GetString("display.times"); times != timesRelative && times != timesLocal {
+		return fmt.Errorf("unsupported display.times %q, expected %q or %q", times, timesRelative, timesLocal)
+	}
+
+	if viper.GetString("db.driver") == driverFile {
+		viper.SetDefault("db.file", path.Join(home, "cpn.json"))
 	}
+
+	return nil
+}
+
+// The drivers that can be used for the db.driver config key.
+const (
+	driverSQLite = "sqlite"
+	driverFile   = "file"
+	driverRemote = "remote"
+)
+
+// clientFunc returns the notes client, opening the store the first time it's called.
+type clientFunc func() (*notes.Client, error)
+
+// clipboardFunc returns the clipboard, creating it the first time it's called. The file
+// backend uses the command's output and input in place of stdout and stdin.
+type clipboardFunc func(cmd *cobra.Command) (clipboard.Clipboard, error)
+
+// dependencies lazily opens what the commands need, using the config loaded by initConfig.
+type dependencies struct {
+	store  notes.NoteReaderWriter
+	client *notes.Client
+	cb     clipboard.Clipboard
 }
 
-func handleBindEnvErr(err error) {
+// Client opens the store for the configured driver, if it isn't already open.
+func (d *dependencies) Client() (*notes.Client, error) {
+	if d.client != nil {
+		return d.client, nil
+	}
+
+	store, err := openStore()
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "unable to bind viper key to environment variable: ", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("unable to open store: %w", err)
 	}
+
+	d.store = store
+	d.client = notes.New(store)
+
+	return d.client, nil
 }
 
-func setupCommands() error {
-	client, err := sqlite.New(viper.GetString("db.file"))
+// Clipboard creates the configured clipboard, if it hasn't already been created.
+func (d *dependencies) Clipboard(cmd *cobra.Command) (clipboard.Clipboard, error) {
+	if d.cb != nil {
+		return d.cb, nil
+	}
+
+	cb, err := clipboard.New(clipboard.Config{
+		Backend: viper.GetString("clipboard.backend"),
+		File:    viper.GetString("clipboard.file"),
+		Stdout:  cmd.OutOrStdout(),
+		Stdin:   cmd.InOrStdin(),
+	})
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("unable to create clipboard: %w", err)
+	}
+
+	d.cb = cb
+
+	return cb, nil
+}
+
+// SQLiteFile returns the database file, as long as the sqlite driver is being used.
+func (d *dependencies) SQLiteFile() (string, error) {
+	if driver := viper.GetString("db.driver"); driver != driverSQLite {
+		return "", fmt.Errorf("migrations are only supported by the %q driver, not %q", driverSQLite, driver)
 	}
 
-	addCmd := newAddCommand(client)
-	getCmd := newGetCommand(client)
-	listCmd := newListCommand(client)
-	copyCmd := newCopyCommand(client)
-	updateCmd := newUpdateCommand(client)
-	deleteCmd := newDeleteCommand(client)
-	migrateCmd := newMigrateCommand(viper.GetString("db.file"))
-
-	rootCmd.AddCommand(addCmd)
-	rootCmd.AddCommand(getCmd)
-	rootCmd.AddCommand(listCmd)
-	rootCmd.AddCommand(copyCmd)
-	rootCmd.AddCommand(updateCmd)
-	rootCmd.AddCommand(deleteCmd)
-	rootCmd.AddCommand(migrateCmd)
+	return viper.GetString("db.file"), nil
+}
+
+// Close closes the store, if it was opened, and forgets the clipboard, so that both are
+// created again if they're needed.
+func (d *dependencies) Close() error {
+	store := d.store
+	d.store, d.client, d.cb = nil, nil, nil
+
+	if c, ok := store.(io.Closer); ok {
+		return c.Close()
+	}
 
 	return nil
 }
+
+// openStore opens the notes store for the configured driver.
+func openStore() (notes.NoteReaderWriter, error) {
+	switch driver := viper.GetString("db.driver"); driver {
+	case driverSQLite:
+		return sqlite.New(viper.GetString("db.file"))
+	case driverFile:
+		return filestore.New(viper.GetString("db.file"))
+	case driverRemote:
+		return remote.New(remote.Config{
+			URL:     viper.GetString("db.url"),
+			Token:   viper.GetString("db.token"),
+			Timeout: viper.GetDuration("db.timeout"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown db.driver %q, expected %q, %q or %q", driver, driverSQLite, driverFile, driverRemote)
+	}
+}
+
+func setupCommands() {
+	rootCmd.AddCommand(newMigrateCommand(deps.SQLiteFile))
+	rootCmd.AddCommand(newAddCommand(deps.Client))
+	rootCmd.AddCommand(newGetCommand(deps.Client))
+	rootCmd.AddCommand(newListCommand(deps.Client))
+	rootCmd.AddCommand(newStatsCommand(deps.Client))
+	rootCmd.AddCommand(newSearchCommand(deps.Client))
+	rootCmd.AddCommand(newCopyCommand(deps.Client, deps.Clipboard))
+	rootCmd.AddCommand(newPasteCommand(deps.Client, deps.Clipboard))
+	rootCmd.AddCommand(newRenderCommand(deps.Client))
+	rootCmd.AddCommand(newUpdateCommand(deps.Client))
+	rootCmd.AddCommand(newEditCommand(deps.Client))
+	rootCmd.AddCommand(newDeleteCommand(deps.Client))
+	rootCmd.AddCommand(newTagCommand(deps.Client))
+	rootCmd.AddCommand(newUntagCommand(deps.Client))
+	rootCmd.AddCommand(newHistoryCommand(deps.Client))
+	rootCmd.AddCommand(newDiffCommand(deps.Client))
+	rootCmd.AddCommand(newRestoreCommand(deps.Client))
+	rootCmd.AddCommand(newTrashCommand(deps.Client))
+	rootCmd.AddCommand(newExportCommand(deps.Client))
+	rootCmd.AddCommand(newImportCommand(deps.Client))
+	rootCmd.AddCommand(newSyncCommand(deps.Client))
+	rootCmd.AddCommand(newServeCommand(deps.Client))
+	rootCmd.AddCommand(newRPCCommand(deps.Client))
+	rootCmd.AddCommand(newShellInitCommand())
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/simondrake/copy-paste-notes/internal/rpc"
+)
+
+func newRPCCommand(openClient clientFunc) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rpc",
+		Short: "Serves the notes over JSON-RPC 2.0 on stdin and stdout",
+		Long: `Serves the notes over JSON-RPC 2.0 on stdin and stdout, with one message per line, until
+stdin is closed. This is intended for editor plugins and other tools that keep cpn running.
+
+The methods are list, get, search, create, update, delete and render, which take their params
+as an object, e.g.
+
+  {"jsonrpc": "2.0", "id": 1, "method": "render", "params": {"title": "pods", "values": {"namespace": "dev"}}}
+
+Notes are encoded in the same shape as "get --format json". A "noteChanged" notification, with
+the note's id and a change of "created", "updated" or "deleted", is sent whenever a note is
+changed.
+
+Errors have stable codes: -32001 when a note isn't found, -32002 when a title is taken,
+-32003 when a note is invalid and -32004 when a placeholder has no value, as well as the
+standard JSON-RPC codes.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			return rpc.New(client).Serve(cmd.InOrStdin(), cmd.OutOrStdout())
+		},
+	}
+}
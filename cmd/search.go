@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+var (
+	// highlighter replaces the search highlight markers with bold/reset ANSI escape codes.
+	highlighter = strings.NewReplacer(notes.HighlightStart, "\033[1m", notes.HighlightEnd, "\033[0m")
+	// unhighlighter removes the search highlight markers, for output that isn't a terminal.
+	unhighlighter = strings.NewReplacer(notes.HighlightStart, "", notes.HighlightEnd, "")
+)
+
+func newSearchCommand(openClient clientFunc) *cobra.Command {
+	var (
+		autoWrapText bool
+		format       string
+	)
+
+	searchCmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Searches the title and description of all notes",
+		Long: `Searches the title and description of all notes.
+
+Results are ranked by relevance (bm25) and words match by prefix when SQLite's full-text
+search extension is available, i.e. when built with -tags sqlite_fts5 or with the pure-Go
+driver. Otherwise notes containing every word of the query are listed, with matches in the
+title first.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			rs, err := client.Search(strings.Join(args, " "))
+			if err != nil {
+				return fmt.Errorf("unable to search notes: %w", err)
+			}
+
+			switch format {
+			case "table":
+				hl := unhighlighter
+				if isTerminal(cmd.OutOrStdout()) {
+					hl = highlighter
+				}
+
+				table := tablewriter.NewWriter(cmd.OutOrStdout())
+				table.SetHeader([]string{"ID", "Title", "Match"})
+				table.SetAutoWrapText(autoWrapText)
+
+				for _, r := range rs {
+					table.Append([]string{fmt.Sprint(r.ID), hl.Replace(r.TitleHighlight), hl.Replace(r.DescriptionHighlight)})
+				}
+
+				table.Render()
+			case "json":
+				return writeJSON(cmd.OutOrStdout(), rs)
+			default:
+				return errUnsupportedFormat
+			}
+
+			return nil
+		},
+	}
+
+	searchCmd.Flags().BoolVarP(&autoWrapText, "autowrap", "w", false, "whether to auto wrap the text output")
+	searchCmd.Flags().StringVarP(&format, "format", "f", "table", "output format to use [table, json]")
+
+	return searchCmd
+}
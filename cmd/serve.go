@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/simondrake/copy-paste-notes/internal/server"
+)
+
+// shutdownTimeout is how long requests in progress are given to finish when the server stops.
+const shutdownTimeout = 10 * time.Second
+
+func newServeCommand(openClient clientFunc) *cobra.Command {
+	var addr string
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serves the notes as a JSON REST API",
+		Long: `Serves the notes as a JSON REST API, until interrupted.
+
+Requests must send the token in the serve.token config key (or the CPN_SERVE_TOKEN environment
+variable) as a bearer token, e.g. "Authorization: Bearer <token>". Notes are encoded in the
+same shape as "get --format json".`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			token := viper.GetString("serve.token")
+			if token == "" {
+				return errors.New("serve.token must be set to serve the notes")
+			}
+
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("unable to listen: %w", err)
+			}
+
+			errLog := log.New(cmd.ErrOrStderr(), "", log.LstdFlags)
+
+			srv := &http.Server{
+				Handler:           server.New(client, token, errLog),
+				ReadHeaderTimeout: 10 * time.Second,
+				ErrorLog:          errLog,
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			served := make(chan error, 1)
+			go func() {
+				served <- srv.Serve(ln)
+			}()
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "serving notes on http://%s\n", ln.Addr())
+
+			select {
+			case err := <-served:
+				return fmt.Errorf("unable to serve: %w", err)
+			case <-ctx.Done():
+			}
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				return fmt.Errorf("unable to shut down server: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	serveCmd.Flags().StringVar(&addr, "addr", "127.0.0.1:8080", "address to listen on")
+
+	return serveCmd
+}
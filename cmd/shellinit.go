@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// The widgets run "copy --stdout", reading from the terminal so that the picker and placeholder
+// prompts work, and insert the note at the cursor. %[1]s is the name of the binary.
+var shellWidgets = map[string]string{
+	"bash": `# Insert a note at the cursor with Ctrl-X Ctrl-N
+__cpn_insert_note() {
+  local note
+  note="$(%[1]s copy --stdout </dev/tty)" || return
+  READLINE_LINE="${READLINE_LINE:0:$READLINE_POINT}${note}${READLINE_LINE:$READLINE_POINT}"
+  READLINE_POINT=$((READLINE_POINT + ${#note}))
+}
+
+bind -m emacs-standard -x '"\C-x\C-n": __cpn_insert_note'
+bind -m vi-insert -x '"\C-x\C-n": __cpn_insert_note'
+`,
+	"zsh": `# Insert a note at the cursor with Ctrl-X Ctrl-N
+__cpn_insert_note() {
+  local note
+  note="$(%[1]s copy --stdout </dev/tty)"
+  local ret=$?
+  if (( ret == 0 )); then
+    LBUFFER+="$note"
+  fi
+  zle reset-prompt
+  return $ret
+}
+
+zle -N __cpn_insert_note
+bindkey -M emacs '^X^N' __cpn_insert_note
+bindkey -M viins '^X^N' __cpn_insert_note
+`,
+	"fish": `# Insert a note at the cursor with Ctrl-X Ctrl-N
+function __cpn_insert_note
+    set -l note (%[1]s copy --stdout </dev/tty | string collect)
+    and commandline --insert -- $note
+    commandline -f repaint
+end
+
+bind \cx\cn __cpn_insert_note
+bind -M insert \cx\cn __cpn_insert_note
+`,
+}
+
+func newShellInitCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "shell-init bash|zsh|fish",
+		Short: "Prints a shell widget that inserts a note at the prompt",
+		Long: `Prints a shell widget that inserts a note into the command line at the cursor when
+Ctrl-X Ctrl-N is pressed, e.g.
+
+  bash: eval "$(copy-paste-notes shell-init bash)" in ~/.bashrc
+  zsh:  eval "$(copy-paste-notes shell-init zsh)" in ~/.zshrc
+  fish: copy-paste-notes shell-init fish | source in ~/.config/fish/config.fish
+
+The note is chosen with the picker and its placeholders filled in, as with copy, but it's
+inserted rather than copied to the clipboard. To use another key, bind __cpn_insert_note
+after the widget is loaded.`,
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Fprintf(cmd.OutOrStdout(), shellWidgets[args[0]], cmd.Root().Name())
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func newStatsCommand(openClient clientFunc) *cobra.Command {
+	var (
+		top    int
+		format string
+	)
+
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Shows the most used notes and totals",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			s, err := client.Stats(top)
+			if err != nil {
+				return fmt.Errorf("unable to get stats: %w", err)
+			}
+
+			switch format {
+			case "table":
+				table := tablewriter.NewWriter(cmd.OutOrStdout())
+				table.SetHeader([]string{"Notes", "Trashed", "Tags", "Copied Notes", "Copies"})
+				table.Append([]string{fmt.Sprint(s.Notes), fmt.Sprint(s.Trashed), fmt.Sprint(s.Tags), fmt.Sprint(s.Used), fmt.Sprint(s.Uses)})
+				table.Render()
+
+				if len(s.Top) == 0 {
+					return nil
+				}
+
+				table = tablewriter.NewWriter(cmd.OutOrStdout())
+				table.SetHeader([]string{"ID", "Title", "Copies", "Last Used"})
+
+				for _, n := range s.Top {
+					table.Append([]string{fmt.Sprint(n.ID), n.Title, fmt.Sprint(n.UseCount), formatTime(n.LastUsedTimestamp)})
+				}
+
+				table.Render()
+			case "json":
+				return writeJSON(cmd.OutOrStdout(), s)
+			default:
+				return errUnsupportedFormat
+			}
+
+			return nil
+		},
+	}
+
+	statsCmd.Flags().IntVar(&top, "top", 5, "number of the most used notes to show")
+	statsCmd.Flags().StringVarP(&format, "format", "f", "table", "output format to use [table, json]")
+
+	return statsCmd
+}
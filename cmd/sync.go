@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/simondrake/copy-paste-notes/internal/mdsync"
+)
+
+func newSyncCommand(openClient clientFunc) *cobra.Command {
+	syncCmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Syncs notes with other locations",
+	}
+
+	syncCmd.AddCommand(newSyncDirCommand(openClient))
+
+	return syncCmd
+}
+
+func newSyncDirCommand(openClient clientFunc) *cobra.Command {
+	dirCmd := &cobra.Command{
+		Use:   "dir <path>",
+		Short: "Syncs notes with a directory of Markdown files, one per note",
+		Long: `Syncs notes with a directory of Markdown files, one per note, creating the directory if needed.
+
+Changes made to either the notes or the files since the last sync are applied to the other side.
+If a note was changed on both sides, the file is left alone and the note is written to a
+.conflict file next to it. Remove the .conflict file once the note's file is correct to
+resolve the conflict.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			r, err := mdsync.Dir(client, args[0])
+			if err != nil {
+				return fmt.Errorf("unable to sync directory: %w", err)
+			}
+
+			fmt.Fprintln(cmd.OutOrStdout(), r)
+
+			for _, c := range r.Conflicts {
+				fmt.Fprintf(cmd.ErrOrStderr(), "conflict: note %d (%s) in %s: %s\n", c.ID, c.Title, c.File, c.Reason)
+			}
+
+			for _, err := range r.Errors {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error: ", err)
+			}
+
+			if len(r.Conflicts) > 0 || len(r.Errors) > 0 {
+				return fmt.Errorf("sync finished with %d conflict(s) and %d error(s)", len(r.Conflicts), len(r.Errors))
+			}
+
+			return nil
+		},
+	}
+
+	return dirCmd
+}
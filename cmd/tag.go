@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newTagCommand(openClient clientFunc) *cobra.Command {
+	var id int
+
+	tagCmd := &cobra.Command{
+		Use:   "tag <tag>...",
+		Short: "Adds tags to a note",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			if err := client.Tag(id, args); err != nil {
+				return fmt.Errorf("unable to tag note: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	tagCmd.Flags().IntVar(&id, "id", 0, "id of the note")
+
+	tagCmd.MarkFlagRequired("id")
+
+	tagCmd.RegisterFlagCompletionFunc("id", completeIDs(openClient))
+
+	return tagCmd
+}
+
+func newUntagCommand(openClient clientFunc) *cobra.Command {
+	var id int
+
+	untagCmd := &cobra.Command{
+		Use:   "untag <tag>...",
+		Short: "Removes tags from a note",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			if err := client.Untag(id, args); err != nil {
+				return fmt.Errorf("unable to untag note: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	untagCmd.Flags().IntVar(&id, "id", 0, "id of the note")
+
+	untagCmd.MarkFlagRequired("id")
+
+	untagCmd.RegisterFlagCompletionFunc("id", completeIDs(openClient))
+
+	return untagCmd
+}
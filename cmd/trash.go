@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+func newTrashCommand(openClient clientFunc) *cobra.Command {
+	trashCmd := &cobra.Command{
+		Use:   "trash",
+		Short: "Manages deleted notes",
+	}
+
+	trashCmd.AddCommand(newTrashListCommand(openClient))
+	trashCmd.AddCommand(newTrashRestoreCommand(openClient))
+	trashCmd.AddCommand(newTrashEmptyCommand(openClient))
+
+	return trashCmd
+}
+
+func newTrashListCommand(openClient clientFunc) *cobra.Command {
+	var format string
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Lists the notes in the trash",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			ns, err := client.Trash()
+			if err != nil {
+				return fmt.Errorf("unable to list trash: %w", err)
+			}
+
+			switch format {
+			case "table":
+				table := tablewriter.NewWriter(cmd.OutOrStdout())
+				table.SetHeader([]string{"ID", "Deleted", "Title"})
+
+				for _, n := range ns {
+					table.Append([]string{fmt.Sprint(n.ID), formatTime(n.DeleteTimestamp), n.Title})
+				}
+
+				table.Render()
+			case "json":
+				return writeJSON(cmd.OutOrStdout(), ns)
+			default:
+				return errUnsupportedFormat
+			}
+
+			return nil
+		},
+	}
+
+	listCmd.Flags().StringVarP(&format, "format", "f", "table", "output format to use [table, json]")
+
+	return listCmd
+}
+
+func newTrashRestoreCommand(openClient clientFunc) *cobra.Command {
+	var id int
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restores a note from the trash",
+		Long: `Restores a note from the trash.
+
+The title of a deleted note can be reused, so a note can't be restored while another note has
+its title. Rename the other note first.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			if err := client.Untrash(id); err != nil {
+				return fmt.Errorf("unable to restore note: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	restoreCmd.Flags().IntVar(&id, "id", 0, "id of the note")
+
+	restoreCmd.MarkFlagRequired("id")
+
+	return restoreCmd
+}
+
+func newTrashEmptyCommand(openClient clientFunc) *cobra.Command {
+	var olderThan string
+
+	emptyCmd := &cobra.Command{
+		Use:   "empty",
+		Short: "Permanently deletes the notes in the trash",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
+			if err != nil {
+				return err
+			}
+
+			var before time.Time
+
+			if olderThan != "" {
+				age, err := parseAge(olderThan)
+				if err != nil {
+					return fmt.Errorf("invalid --older-than value: %w", err)
+				}
+
+				before = time.Now().Add(-age)
+			}
+
+			deleted, err := client.EmptyTrash(before)
+			if err != nil {
+				return fmt.Errorf("unable to empty trash: %w", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "permanently deleted %d note(s)\n", deleted)
+
+			return nil
+		},
+	}
+
+	emptyCmd.Flags().StringVar(&olderThan, "older-than", "", "only delete notes that have been in the trash for longer than this (e.g. 30d, 2w, 12h)")
+
+	return emptyCmd
+}
+
+// parseAge parses a duration, additionally accepting a number of days (e.g. 30d) or weeks (e.g. 2w).
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n := strings.TrimSuffix(s, suffix)
+
+			v, err := strconv.Atoi(n)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("invalid number of %s: %q", suffix, n)
+			}
+
+			return time.Duration(v) * unit, nil
+		}
+	}
+
+	return time.ParseDuration(s)
+}
@@ -2,30 +2,47 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/spf13/cobra"
 
 	"github.com/simondrake/copy-paste-notes/internal/notes"
-	"github.com/simondrake/copy-paste-notes/internal/sqlite"
 )
 
-func newUpdateCommand(client *sqlite.Client) *cobra.Command {
+func newUpdateCommand(openClient clientFunc) *cobra.Command {
 	var (
 		id          int
 		title       string
 		description string
+		tags        []string
 	)
 
 	addCmd := &cobra.Command{
 		Use:   "update",
 		Short: "Updates a note",
-		Run: func(_ *cobra.Command, _ []string) {
-			_, err := client.UpdateNote(id, notes.Note{Title: title, Description: description})
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := openClient()
 			if err != nil {
-				fmt.Fprintln(os.Stderr, "unable to update note: ", err)
-				os.Exit(1)
+				return err
 			}
+
+			n := notes.Note{Title: title, Description: description}
+
+			// Only replace the tags when the flag is given, so they can be cleared with --tag=""
+			if cmd.Flags().Changed("tag") {
+				n.Tags = make([]string, 0, len(tags))
+				for _, t := range tags {
+					if t != "" {
+						n.Tags = append(n.Tags, t)
+					}
+				}
+			}
+
+			_, err = client.Update(id, n)
+			if err != nil {
+				return fmt.Errorf("unable to update note: %w", err)
+			}
+
+			return nil
 		},
 	}
 
@@ -33,8 +50,12 @@ func newUpdateCommand(client *sqlite.Client) *cobra.Command {
 	addCmd.Flags().StringVarP(&title, "title", "t", "", "title of the note")
 	addCmd.Flags().StringVarP(&description, "description", "d", "", "description of the note")
 
+	addCmd.Flags().StringSliceVar(&tags, "tag", nil, "replace the tags of the note (can be repeated)")
+
 	addCmd.MarkFlagRequired("id")
-	addCmd.MarkFlagsOneRequired("title", "description")
+	addCmd.MarkFlagsOneRequired("title", "description", "tag")
+
+	addCmd.RegisterFlagCompletionFunc("id", completeIDs(openClient))
 
 	return addCmd
 }
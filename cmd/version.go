@@ -13,8 +13,8 @@ func init() {
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number of copy-paste-notes",
-	Run: func(_ *cobra.Command, _ []string) {
+	Run: func(cmd *cobra.Command, _ []string) {
 		// TODO - use the actual version
-		fmt.Println("Copy Paste Notes v0.0.1")
+		fmt.Fprintln(cmd.OutOrStdout(), "Copy Paste Notes v0.0.1")
 	},
 }
@@ -0,0 +1,328 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	xclipboard "golang.design/x/clipboard"
+)
+
+// Backend names, as used by the clipboard.backend config key.
+const (
+	BackendAuto   = "auto"
+	BackendNative = "native"
+	BackendWLCopy = "wl-copy"
+	BackendXClip  = "xclip"
+	BackendXSel   = "xsel"
+	BackendTmux   = "tmux"
+	BackendOSC52  = "osc52"
+	BackendFile   = "file"
+)
+
+var (
+	ErrUnknownBackend   = errors.New("unknown clipboard backend")
+	ErrPasteUnsupported = errors.New("the clipboard backend does not support pasting")
+)
+
+// Clipboard is somewhere that text can be copied to and pasted from.
+type Clipboard interface {
+	Copy(text string) error
+	// Paste returns the current contents of the clipboard, or ErrPasteUnsupported if the
+	// clipboard can't be read.
+	Paste() (string, error)
+}
+
+type Config struct {
+	// Backend is the name of the backend to use, or BackendAuto to detect one.
+	Backend string
+	// File is the path written to by the file backend, where "-" or "" is stdout.
+	File string
+	// Stdout and Stdin are used by the file backend in place of os.Stdout and os.Stdin.
+	Stdout io.Writer
+	Stdin  io.Reader
+}
+
+// New returns the clipboard for the configured backend.
+func New(cfg Config) (Clipboard, error) {
+	switch cfg.Backend {
+	case BackendAuto, "":
+		return Detect(), nil
+	case BackendNative:
+		return Native{}, nil
+	case BackendWLCopy:
+		return WLCopy, nil
+	case BackendXClip:
+		return XClip, nil
+	case BackendXSel:
+		return XSel, nil
+	case BackendTmux:
+		return Tmux, nil
+	case BackendOSC52:
+		return OSC52{Passthrough: DetectPassthrough()}, nil
+	case BackendFile:
+		return File{Path: cfg.File, W: cfg.Stdout, R: cfg.Stdin}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownBackend, cfg.Backend)
+	}
+}
+
+// Detect picks a clipboard based on the environment. SSH sessions use OSC 52 so that the text
+// lands in the clipboard of the local terminal, even when X forwarding sets a display that
+// would copy to the remote X server instead. Wayland sessions use wl-copy, as the native
+// clipboard package only supports X11. On Linux (and other X11 platforms) sessions without a
+// display also use OSC 52. Everything else uses the native clipboard.
+func Detect() Clipboard {
+	if os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != "" {
+		return OSC52{Passthrough: DetectPassthrough()}
+	}
+
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		return WLCopy
+	}
+
+	if os.Getenv("DISPLAY") == "" && runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		return OSC52{Passthrough: DetectPassthrough()}
+	}
+
+	return Native{}
+}
+
+// Native uses golang.design/x/clipboard, which talks to X11 on Linux and the system
+// clipboard on macOS and Windows.
+type Native struct{}
+
+// x11HandoffDelay is how long to keep running after writing to an X11 clipboard.
+const x11HandoffDelay = 500 * time.Millisecond
+
+func (Native) Copy(text string) error {
+	if err := xclipboard.Init(); err != nil {
+		return err
+	}
+
+	xclipboard.Write(xclipboard.FmtText, []byte(text))
+
+	// X11 has no clipboard storage of its own: the selection is served by whichever process
+	// owns it, and is lost when that process exits. Give clipboard managers a moment to take
+	// a copy before we do.
+	if runtime.GOOS != "darwin" && runtime.GOOS != "windows" {
+		time.Sleep(x11HandoffDelay)
+	}
+
+	return nil
+}
+
+func (Native) Paste() (string, error) {
+	if err := xclipboard.Init(); err != nil {
+		return "", err
+	}
+
+	return string(xclipboard.Read(xclipboard.FmtText)), nil
+}
+
+// Command copies by writing the text to the stdin of an external command, and pastes by
+// reading the stdout of another. Each is the name of the command followed by its arguments.
+type Command struct {
+	CopyCmd  []string
+	PasteCmd []string
+}
+
+var (
+	WLCopy = Command{CopyCmd: []string{"wl-copy"}, PasteCmd: []string{"wl-paste", "--no-newline"}}
+	XClip  = Command{CopyCmd: []string{"xclip", "-selection", "clipboard"}, PasteCmd: []string{"xclip", "-selection", "clipboard", "-out"}}
+	XSel   = Command{CopyCmd: []string{"xsel", "--clipboard", "--input"}, PasteCmd: []string{"xsel", "--clipboard", "--output"}}
+	Tmux   = Command{CopyCmd: []string{"tmux", "load-buffer", "-"}, PasteCmd: []string{"tmux", "save-buffer", "-"}}
+)
+
+func (c Command) Copy(text string) error {
+	// The output isn't captured, as wl-copy and xclip fork a process that serves the clipboard
+	// in the background, which would otherwise hold the pipes open.
+	cmd := exec.Command(c.CopyCmd[0], c.CopyCmd[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("unable to run %s: %w", c.CopyCmd[0], err)
+	}
+
+	return nil
+}
+
+func (c Command) Paste() (string, error) {
+	out, err := exec.Command(c.PasteCmd[0], c.PasteCmd[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("unable to run %s: %w", c.PasteCmd[0], err)
+	}
+
+	return string(out), nil
+}
+
+// OSC52 copies by writing an OSC 52 escape sequence, which asks the terminal emulator itself
+// to set the clipboard. This works over SSH, as the sequence travels back to the local terminal.
+type OSC52 struct {
+	// W is where the escape sequence is written. If nil, the controlling terminal is used.
+	W io.Writer
+	// Passthrough wraps the sequence so that it is passed on by a terminal multiplexer.
+	Passthrough Passthrough
+}
+
+type Passthrough string
+
+const (
+	PassthroughNone   Passthrough = ""
+	PassthroughTmux   Passthrough = "tmux"
+	PassthroughScreen Passthrough = "screen"
+)
+
+// MaxOSC52Size is the largest base64 encoded payload that will be sent in an OSC 52 sequence.
+// Terminals silently drop sequences over their own limits, which are commonly around this size.
+const MaxOSC52Size = 100000
+
+var ErrTooLarge = errors.New("text is too large to copy using OSC 52")
+
+// screenChunkSize is the size of the pieces the sequence is split into for screen, which
+// limits the length of the strings it will pass through.
+const screenChunkSize = 76
+
+// DetectPassthrough returns the passthrough needed by the terminal multiplexer being run in, if any.
+func DetectPassthrough() Passthrough {
+	if os.Getenv("TMUX") != "" {
+		return PassthroughTmux
+	}
+
+	if os.Getenv("STY") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		return PassthroughScreen
+	}
+
+	return PassthroughNone
+}
+
+func (o OSC52) Copy(text string) error {
+	seq, err := o.sequence(text)
+	if err != nil {
+		return err
+	}
+
+	w := o.W
+	if w == nil {
+		tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("unable to open terminal: %w", err)
+		}
+
+		defer tty.Close()
+
+		w = tty
+	}
+
+	_, err = io.WriteString(w, seq)
+	return err
+}
+
+// Paste isn't supported, as few terminals allow the clipboard to be read using OSC 52.
+func (OSC52) Paste() (string, error) {
+	return "", ErrPasteUnsupported
+}
+
+func (o OSC52) sequence(text string) (string, error) {
+	payload := base64.StdEncoding.EncodeToString([]byte(text))
+	if len(payload) > MaxOSC52Size {
+		return "", fmt.Errorf("%w: it is %d bytes once encoded, the limit is %d", ErrTooLarge, len(payload), MaxOSC52Size)
+	}
+
+	seq := "\x1b]52;c;" + payload + "\x07"
+
+	switch o.Passthrough {
+	case PassthroughTmux:
+		// tmux passes through DCS sequences starting with "tmux;", with any ESCs doubled
+		return "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\", nil
+	case PassthroughScreen:
+		var b strings.Builder
+
+		for len(seq) > 0 {
+			n := screenChunkSize
+			if len(seq) < n {
+				n = len(seq)
+			}
+
+			b.WriteString("\x1bP" + seq[:n] + "\x1b\\")
+			seq = seq[n:]
+		}
+
+		return b.String(), nil
+	default:
+		return seq, nil
+	}
+}
+
+// File copies by writing the text to a file, or to stdout if the path is "-" or empty, and
+// pastes by reading it back, or reading stdin.
+type File struct {
+	Path string
+	// W is written to in place of stdout. If nil, os.Stdout is used.
+	W io.Writer
+	// R is read from in place of stdin. If nil, os.Stdin is used.
+	R io.Reader
+}
+
+func (f File) Copy(text string) error {
+	if f.Path == "" || f.Path == "-" {
+		w := f.W
+		if w == nil {
+			w = os.Stdout
+		}
+
+		_, err := io.WriteString(w, text)
+		return err
+	}
+
+	return os.WriteFile(f.Path, []byte(text), 0o600)
+}
+
+func (f File) Paste() (string, error) {
+	if f.Path == "" || f.Path == "-" {
+		r := f.R
+		if r == nil {
+			r = os.Stdin
+		}
+
+		b, err := io.ReadAll(r)
+		return string(b), err
+	}
+
+	b, err := os.ReadFile(f.Path)
+	return string(b), err
+}
+
+// Memory is an in-memory clipboard, intended for tests.
+type Memory struct {
+	mu   sync.Mutex
+	text string
+}
+
+func (m *Memory) Copy(text string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.text = text
+
+	return nil
+}
+
+func (m *Memory) Paste() (string, error) {
+	return m.Text(), nil
+}
+
+// Text returns the last text copied.
+func (m *Memory) Text() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.text
+}
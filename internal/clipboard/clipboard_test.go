@@ -0,0 +1,198 @@
+package clipboard
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("should return the configured backend", func(t *testing.T) {
+		cb, err := New(Config{Backend: BackendXSel})
+		assert.NoError(t, err)
+		assert.Equal(t, XSel, cb)
+
+		cb, err = New(Config{Backend: BackendFile, File: "/tmp/clipboard"})
+		assert.NoError(t, err)
+		assert.Equal(t, File{Path: "/tmp/clipboard"}, cb)
+	})
+
+	t.Run("should return an error for an unknown backend", func(t *testing.T) {
+		cb, err := New(Config{Backend: "carrier-pigeon"})
+		assert.Nil(t, cb)
+		assert.ErrorIs(t, err, ErrUnknownBackend)
+	})
+
+	t.Run("should use wl-copy for wayland sessions", func(t *testing.T) {
+		t.Setenv("WAYLAND_DISPLAY", "wayland-0")
+
+		cb, err := New(Config{Backend: BackendAuto})
+		assert.NoError(t, err)
+		assert.Equal(t, WLCopy, cb)
+	})
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		want   Clipboard
+		skipOn []string
+	}{
+		{
+			name: "osc52 when there is no display",
+			env:  map[string]string{"DISPLAY": "", "TMUX": "/tmp/tmux-1000/default,1234,0"},
+			want: OSC52{Passthrough: PassthroughTmux},
+			// macOS and Windows have a clipboard without a display
+			skipOn: []string{"darwin", "windows"},
+		},
+		{
+			name: "native when there is a display",
+			env:  map[string]string{"DISPLAY": ":0"},
+			want: Native{},
+		},
+		{
+			name: "wl-copy under wayland",
+			env:  map[string]string{"WAYLAND_DISPLAY": "wayland-0", "DISPLAY": ":0"},
+			want: WLCopy,
+		},
+		{
+			name: "osc52 over ssh with x forwarding",
+			env:  map[string]string{"SSH_CONNECTION": "10.0.0.2 51234 10.0.0.1 22", "DISPLAY": "localhost:10.0"},
+			want: OSC52{},
+		},
+		{
+			name: "osc52 over ssh with a tty",
+			env:  map[string]string{"SSH_TTY": "/dev/pts/1", "WAYLAND_DISPLAY": "wayland-0"},
+			want: OSC52{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("should use "+tt.name, func(t *testing.T) {
+			for _, goos := range tt.skipOn {
+				if runtime.GOOS == goos {
+					t.Skipf("not detected on %s", goos)
+				}
+			}
+
+			for _, key := range []string{"SSH_TTY", "SSH_CONNECTION", "WAYLAND_DISPLAY", "DISPLAY", "TMUX", "STY", "TERM"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			assert.Equal(t, tt.want, Detect())
+		})
+	}
+}
+
+func TestDetectPassthrough(t *testing.T) {
+	tests := []struct {
+		name string
+		tmux string
+		sty  string
+		term string
+		want Passthrough
+	}{
+		{name: "none", term: "xterm-256color", want: PassthroughNone},
+		{name: "tmux", tmux: "/tmp/tmux-1000/default,1234,0", term: "screen-256color", want: PassthroughTmux},
+		{name: "screen session", sty: "1234.pts-0.host", term: "xterm", want: PassthroughScreen},
+		{name: "screen term", term: "screen", want: PassthroughScreen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TMUX", tt.tmux)
+			t.Setenv("STY", tt.sty)
+			t.Setenv("TERM", tt.term)
+
+			assert.Equal(t, tt.want, DetectPassthrough())
+		})
+	}
+}
+
+func TestOSC52(t *testing.T) {
+	t.Run("should write the escape sequence", func(t *testing.T) {
+		var out bytes.Buffer
+
+		require.NoError(t, OSC52{W: &out}.Copy("hello"))
+		assert.Equal(t, "\x1b]52;c;aGVsbG8=\x07", out.String())
+	})
+
+	t.Run("should wrap the sequence for tmux", func(t *testing.T) {
+		var out bytes.Buffer
+
+		require.NoError(t, OSC52{W: &out, Passthrough: PassthroughTmux}.Copy("hello"))
+		assert.Equal(t, "\x1bPtmux;\x1b\x1b]52;c;aGVsbG8=\x07\x1b\\", out.String())
+	})
+
+	t.Run("should wrap the sequence in chunks for screen", func(t *testing.T) {
+		var out bytes.Buffer
+
+		require.NoError(t, OSC52{W: &out, Passthrough: PassthroughScreen}.Copy(strings.Repeat("a", 60)))
+
+		chunks := strings.Split(strings.TrimSuffix(out.String(), "\x1b\\"), "\x1b\\")
+		require.Len(t, chunks, 2)
+
+		var seq string
+		for _, c := range chunks {
+			require.True(t, strings.HasPrefix(c, "\x1bP"))
+			seq += strings.TrimPrefix(c, "\x1bP")
+		}
+
+		assert.Equal(t, "\x1b]52;c;"+base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 60)))+"\x07", seq)
+	})
+
+	t.Run("should not support pasting", func(t *testing.T) {
+		_, err := OSC52{}.Paste()
+		assert.ErrorIs(t, err, ErrPasteUnsupported)
+	})
+
+	t.Run("should return an error when the text is too large", func(t *testing.T) {
+		var out bytes.Buffer
+
+		err := OSC52{W: &out}.Copy(strings.Repeat("a", MaxOSC52Size))
+		assert.ErrorIs(t, err, ErrTooLarge)
+		assert.Zero(t, out.Len())
+	})
+}
+
+func TestFile(t *testing.T) {
+	p := path.Join(t.TempDir(), "clipboard")
+
+	require.NoError(t, File{Path: p}.Copy("hello"))
+
+	b, err := os.ReadFile(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	text, err := File{Path: p}.Paste()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", text)
+
+	var out bytes.Buffer
+
+	require.NoError(t, File{Path: "-", W: &out}.Copy("to stdout"))
+	assert.Equal(t, "to stdout", out.String())
+
+	text, err = File{R: strings.NewReader("from stdin")}.Paste()
+	assert.NoError(t, err)
+	assert.Equal(t, "from stdin", text)
+}
+
+func TestMemory(t *testing.T) {
+	m := &Memory{}
+
+	require.NoError(t, m.Copy("hello"))
+	assert.Equal(t, "hello", m.Text())
+
+	text, err := m.Paste()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", text)
+}
@@ -0,0 +1,166 @@
+package editor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+const frontMatterDelimiter = "---"
+
+var (
+	ErrCancelled          = errors.New("edit cancelled, the note was left empty or unchanged")
+	ErrInvalidFrontMatter = errors.New("invalid front matter")
+	ErrMissingTitle       = errors.New("the note must have a title")
+	ErrMissingDescription = errors.New("the note must have a description")
+)
+
+// Command returns the user's editor from $VISUAL or $EDITOR, falling back to vi.
+func Command() string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if e := strings.TrimSpace(os.Getenv(env)); e != "" {
+			return e
+		}
+	}
+
+	return "vi"
+}
+
+// Edit opens the note in the user's editor and returns the edited note. ErrCancelled is
+// returned if the file is saved empty or unchanged.
+func Edit(n notes.Note) (notes.Note, error) {
+	before := Format(n)
+
+	after, err := editFile(before)
+	if err != nil {
+		return notes.Note{}, err
+	}
+
+	if len(bytes.TrimSpace(after)) == 0 || bytes.Equal(before, after) {
+		return notes.Note{}, ErrCancelled
+	}
+
+	return Parse(after)
+}
+
+func editFile(content []byte) ([]byte, error) {
+	f, err := os.CreateTemp("", "cpn-*.md")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	// The editor may include arguments, e.g. "code --wait"
+	args := strings.Fields(Command())
+
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("unable to run editor %q: %w", args[0], err)
+	}
+
+	return os.ReadFile(f.Name())
+}
+
+// Format renders a note as a small front matter header, holding the title and tags,
+// followed by the description.
+func Format(n notes.Note) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintln(&b, frontMatterDelimiter)
+	fmt.Fprintf(&b, "title: %s\n", n.Title)
+	fmt.Fprintf(&b, "tags: %s\n", strings.Join(n.Tags, ", "))
+	fmt.Fprintln(&b, frontMatterDelimiter)
+	fmt.Fprintln(&b, n.Description)
+
+	return b.Bytes()
+}
+
+// Parse reads a note in the format written by Format.
+func Parse(b []byte) (notes.Note, error) {
+	n := notes.Note{}
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, len(b)+1)
+	if !s.Scan() || strings.TrimSpace(s.Text()) != frontMatterDelimiter {
+		return n, fmt.Errorf("%w: the file must start with %q", ErrInvalidFrontMatter, frontMatterDelimiter)
+	}
+
+	closed := false
+	for s.Scan() {
+		line := s.Text()
+		if strings.TrimSpace(line) == frontMatterDelimiter {
+			closed = true
+			break
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return n, fmt.Errorf("%w: expected \"key: value\" but got %q", ErrInvalidFrontMatter, line)
+		}
+
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "title":
+			n.Title = value
+		case "tags":
+			n.Tags = make([]string, 0)
+			for _, t := range strings.Split(value, ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					n.Tags = append(n.Tags, t)
+				}
+			}
+		default:
+			return n, fmt.Errorf("%w: unknown key %q", ErrInvalidFrontMatter, key)
+		}
+	}
+
+	if !closed {
+		return n, fmt.Errorf("%w: missing closing %q", ErrInvalidFrontMatter, frontMatterDelimiter)
+	}
+
+	var desc []string
+	for s.Scan() {
+		desc = append(desc, s.Text())
+	}
+
+	if err := s.Err(); err != nil {
+		return n, err
+	}
+
+	n.Description = strings.Trim(strings.Join(desc, "\n"), "\n")
+
+	if n.Title == "" {
+		return n, ErrMissingTitle
+	}
+
+	if strings.TrimSpace(n.Description) == "" {
+		return n, ErrMissingDescription
+	}
+
+	return n, nil
+}
@@ -0,0 +1,63 @@
+package editor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+func TestCommand(t *testing.T) {
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	assert.Equal(t, "vi", Command())
+
+	t.Setenv("EDITOR", "nano")
+	assert.Equal(t, "nano", Command())
+
+	t.Setenv("VISUAL", "code --wait")
+	assert.Equal(t, "code --wait", Command())
+}
+
+func TestFormatParse(t *testing.T) {
+	t.Run("should round trip a note", func(t *testing.T) {
+		n := notes.Note{
+			Title:       "kubectl logs",
+			Description: "kubectl -n default \\\\\n  logs my-pod",
+			Tags:        []string{"k8s", "logs"},
+		}
+
+		assert.Equal(t, "---\ntitle: kubectl logs\ntags: k8s, logs\n---\nkubectl -n default \\\\\n  logs my-pod\n", string(Format(n)))
+
+		got, err := Parse(Format(n))
+		require.NoError(t, err)
+		assert.Equal(t, n, got)
+	})
+
+	t.Run("should return an empty slice when there are no tags", func(t *testing.T) {
+		got, err := Parse([]byte("---\ntitle: t\ntags:\n---\nd"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{}, got.Tags)
+	})
+
+	tests := []struct {
+		name  string
+		input string
+		err   error
+	}{
+		{name: "no front matter", input: "title: t\nd", err: ErrInvalidFrontMatter},
+		{name: "unclosed front matter", input: "---\ntitle: t\nd", err: ErrInvalidFrontMatter},
+		{name: "unknown key", input: "---\nname: t\n---\nd", err: ErrInvalidFrontMatter},
+		{name: "missing title", input: "---\ntitle:\n---\nd", err: ErrMissingTitle},
+		{name: "missing description", input: "---\ntitle: t\n---\n\n", err: ErrMissingDescription},
+	}
+
+	for _, tt := range tests {
+		t.Run("should return an error for "+tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input))
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
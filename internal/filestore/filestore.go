@@ -0,0 +1,515 @@
+// Package filestore implements notes.NoteReaderWriter on top of a single JSON document.
+// It doesn't need CGO, so it works in builds where the sqlite driver isn't available.
+//
+// Every write takes a lock file, reads the document, changes it and then atomically replaces
+// the file by renaming a temporary file over it, so readers never see a partial write.
+package filestore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+var (
+	ErrDeleteFailed    = fmt.Errorf("delete failed: %w", notes.ErrNotFound)
+	ErrEmptyQuery      = errors.New("search query must not be empty")
+	ErrRestoreFailed   = fmt.Errorf("restore failed: %w", notes.ErrNotFound)
+	ErrUnknownVersion  = errors.New("unknown notes file version")
+	ErrNothingToUpdate = errors.New("at least one field to update must be provided")
+)
+
+// documentVersion is the version of the document format written by this package. Version 1
+// stored timestamps in local time, rather than as RFC3339 in UTC.
+const documentVersion = 2
+
+type document struct {
+	Version int      `json:"version"`
+	NextID  int      `json:"nextId"`
+	Notes   []record `json:"notes"`
+}
+
+// Timestamps are stored in the format of notes.FormatTimestamp, or are empty if they aren't
+// set.
+type record struct {
+	ID                int        `json:"id"`
+	Title             string     `json:"title"`
+	Description       string     `json:"description"`
+	CreateTimestamp   string     `json:"createTimestamp"`
+	UpdateTimestamp   string     `json:"updateTimestamp,omitempty"`
+	LastUsedTimestamp string     `json:"lastUsedTimestamp,omitempty"`
+	UseCount          int        `json:"useCount,omitempty"`
+	DeleteTimestamp   string     `json:"deleteTimestamp,omitempty"`
+	Tags              []string   `json:"tags,omitempty"`
+	Revisions         []revision `json:"revisions,omitempty"`
+}
+
+type revision struct {
+	NoteID          int    `json:"noteId"`
+	Revision        int    `json:"revision"`
+	CreateTimestamp string `json:"createTimestamp"`
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+}
+
+func (r revision) revision() notes.Revision {
+	return notes.Revision{
+		NoteID:          r.NoteID,
+		Revision:        r.Revision,
+		CreateTimestamp: parseTimestamp(r.CreateTimestamp),
+		Title:           r.Title,
+		Description:     r.Description,
+	}
+}
+
+type Client struct {
+	file string
+}
+
+// New returns a client for the notes in the given file, creating it if it doesn't exist.
+func New(file string) (*Client, error) {
+	c := &Client{file: file}
+
+	if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+		if err := c.update(func(*document) error { return nil }); err != nil {
+			return nil, err
+		}
+
+		return c, nil
+	}
+
+	if _, err := c.read(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Close is a no-op, as the file is only opened for the duration of each call. It exists so
+// that the client can be closed like the other stores.
+func (c *Client) Close() error {
+	return nil
+}
+
+func (c *Client) read() (*document, error) {
+	b, err := os.ReadFile(c.file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &document{Version: documentVersion, NextID: 1, Notes: []record{}}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	d := &document{}
+	if err := json.Unmarshal(b, d); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", c.file, err)
+	}
+
+	if d.Version < 1 || d.Version > documentVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, d.Version)
+	}
+
+	// Version 1 timestamps are converted here, and written in the current version by the next
+	// update
+	if err := d.normaliseTimestamps(); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", c.file, err)
+	}
+
+	d.Version = documentVersion
+
+	return d, nil
+}
+
+// normaliseTimestamps converts every timestamp to the format of notes.FormatTimestamp, which
+// makes sure they're all valid so parseTimestamp can't fail.
+func (d *document) normaliseTimestamps() error {
+	for i := range d.Notes {
+		r := &d.Notes[i]
+
+		ts := []*string{&r.CreateTimestamp, &r.UpdateTimestamp, &r.LastUsedTimestamp, &r.DeleteTimestamp}
+		for j := range r.Revisions {
+			ts = append(ts, &r.Revisions[j].CreateTimestamp)
+		}
+
+		for _, s := range ts {
+			if *s == "" {
+				continue
+			}
+
+			t, err := notes.ParseTimestamp(*s)
+			if err != nil {
+				return fmt.Errorf("note %d: %w", r.ID, err)
+			}
+
+			*s = notes.FormatTimestamp(t)
+		}
+	}
+
+	return nil
+}
+
+// parseTimestamp parses a timestamp that has been normalised, returning the zero time if it
+// is empty.
+func parseTimestamp(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+
+	t, _ := notes.ParseTimestamp(s)
+
+	return t
+}
+
+// timestamp returns how t is stored, which is empty if it is zero.
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return notes.FormatTimestamp(t)
+}
+
+// update applies fn to the document while holding the lock, and writes the result if fn
+// doesn't return an error.
+func (c *Client) update(fn func(*document) error) error {
+	unlock, err := lock(c.file)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	d, err := c.read()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(d); err != nil {
+		return err
+	}
+
+	return c.write(d)
+}
+
+func (c *Client) write(d *document) error {
+	b, err := json.MarshalIndent(d, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.file), filepath.Base(c.file)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.file)
+}
+
+// find returns the note with the given ID, whether or not it's in the trash.
+func (d *document) find(id int) *record {
+	for i := range d.Notes {
+		if d.Notes[i].ID == id {
+			return &d.Notes[i]
+		}
+	}
+
+	return nil
+}
+
+// live returns the note with the given ID if it isn't in the trash.
+func (d *document) live(id int) *record {
+	if r := d.find(id); r != nil && r.DeleteTimestamp == "" {
+		return r
+	}
+
+	return nil
+}
+
+// titleTaken reports whether a note other than the one with the given ID, and that isn't in
+// the trash, has the title.
+func (d *document) titleTaken(title string, id int) bool {
+	r := d.byTitle(title)
+
+	return r != nil && r.ID != id
+}
+
+func (d *document) insert(n notes.Note, tags []string) (int, error) {
+	if d.titleTaken(n.Title, 0) {
+		return 0, fmt.Errorf("%w: %q", notes.ErrDuplicateTitle, n.Title)
+	}
+
+	id := d.NextID
+	d.NextID++
+
+	d.Notes = append(d.Notes, record{
+		ID:              id,
+		Title:           n.Title,
+		Description:     n.Description,
+		CreateTimestamp: timestamp(n.CreateTimestamp),
+		Tags:            sortedTags(tags),
+	})
+
+	return id, nil
+}
+
+// saveRevision copies the current title and description of a note into the next revision.
+func (r *record) saveRevision() {
+	r.Revisions = append(r.Revisions, revision{
+		NoteID:          r.ID,
+		Revision:        len(r.Revisions) + 1,
+		CreateTimestamp: now(),
+		Title:           r.Title,
+		Description:     r.Description,
+	})
+}
+
+func (r *record) note() notes.Note {
+	return notes.Note{
+		ID:                r.ID,
+		Title:             r.Title,
+		Description:       r.Description,
+		CreateTimestamp:   parseTimestamp(r.CreateTimestamp),
+		UpdateTimestamp:   parseTimestamp(r.UpdateTimestamp),
+		LastUsedTimestamp: parseTimestamp(r.LastUsedTimestamp),
+		UseCount:          r.UseCount,
+		DeleteTimestamp:   parseTimestamp(r.DeleteTimestamp),
+		Tags:              sortedTags(r.Tags),
+	}
+}
+
+func sortedTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	out := append([]string(nil), tags...)
+	sort.Strings(out)
+
+	return out
+}
+
+func now() string {
+	return notes.FormatTimestamp(time.Now())
+}
+
+func (c *Client) ListNotes() ([]notes.Note, error) {
+	d, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]notes.Note, 0, len(d.Notes))
+	for _, r := range d.Notes {
+		if r.DeleteTimestamp == "" {
+			out = append(out, r.note())
+		}
+	}
+
+	return out, nil
+}
+
+// SearchNotes returns the notes whose title or description contain every term in the query,
+// ignoring case. Matches in the title rank higher than matches in the description.
+func (c *Client) SearchNotes(query string) ([]notes.SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, ErrEmptyQuery
+	}
+
+	ns, err := c.ListNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	return notes.MatchNotes(ns, query), nil
+}
+
+func (c *Client) GetNoteByID(id int) (*notes.Note, error) {
+	d, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+
+	r := d.live(id)
+	if r == nil {
+		return nil, notes.ErrNotFound
+	}
+
+	n := r.note()
+
+	return &n, nil
+}
+
+func (c *Client) GetNoteByTitle(title string) (*notes.Note, error) {
+	d, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range d.Notes {
+		if r.Title == title && r.DeleteTimestamp == "" {
+			n := r.note()
+			return &n, nil
+		}
+	}
+
+	return nil, notes.ErrNotFound
+}
+
+func (c *Client) InsertNote(n notes.Note) (int, error) {
+	tags, err := notes.NormaliseTags(n.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+
+	err = c.update(func(d *document) error {
+		id, err = d.insert(n, tags)
+		return err
+	})
+
+	return id, err
+}
+
+func (c *Client) UpdateNote(id int, note notes.Note) (int64, error) {
+	if note.Title == "" && note.Description == "" && note.Tags == nil {
+		return 0, ErrNothingToUpdate
+	}
+
+	tags, err := notes.NormaliseTags(note.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	var affected int64
+
+	err = c.update(func(d *document) error {
+		r := d.live(id)
+		if r == nil {
+			return nil
+		}
+
+		if note.Title != "" && d.titleTaken(note.Title, id) {
+			return fmt.Errorf("%w: %q", notes.ErrDuplicateTitle, note.Title)
+		}
+
+		if note.Title != "" || note.Description != "" {
+			r.saveRevision()
+		}
+
+		if note.Title != "" {
+			r.Title = note.Title
+		}
+
+		if note.Description != "" {
+			r.Description = note.Description
+		}
+
+		if tags != nil {
+			r.Tags = tags
+		}
+
+		r.UpdateTimestamp = now()
+		affected = 1
+
+		return nil
+	})
+
+	return affected, err
+}
+
+// DeleteNote moves a note to the trash. Use PurgeNote to delete it permanently.
+func (c *Client) DeleteNote(id int) error {
+	return c.update(func(d *document) error {
+		r := d.live(id)
+		if r == nil {
+			return ErrDeleteFailed
+		}
+
+		r.DeleteTimestamp = now()
+
+		return nil
+	})
+}
+
+func (c *Client) TagNote(id int, tags []string) error {
+	return c.changeTags(id, tags, func(r *record, tags []string) {
+		r.Tags, _ = notes.NormaliseTags(append(r.Tags, tags...))
+	})
+}
+
+func (c *Client) UntagNote(id int, tags []string) error {
+	return c.changeTags(id, tags, func(r *record, tags []string) {
+		remove := make(map[string]bool, len(tags))
+		for _, t := range tags {
+			remove[t] = true
+		}
+
+		kept := make([]string, 0, len(r.Tags))
+		for _, t := range r.Tags {
+			if !remove[t] {
+				kept = append(kept, t)
+			}
+		}
+
+		r.Tags = kept
+	})
+}
+
+func (c *Client) changeTags(id int, tags []string, change func(*record, []string)) error {
+	tags, err := notes.NormaliseTags(tags)
+	if err != nil {
+		return err
+	}
+
+	return c.update(func(d *document) error {
+		r := d.live(id)
+		if r == nil {
+			return notes.ErrNotFound
+		}
+
+		change(r, tags)
+		r.UpdateTimestamp = now()
+
+		return nil
+	})
+}
+
+func (c *Client) MarkNoteUsed(id int) error {
+	return c.update(func(d *document) error {
+		r := d.live(id)
+		if r == nil {
+			return notes.ErrNotFound
+		}
+
+		r.LastUsedTimestamp = now()
+		r.UseCount++
+
+		return nil
+	})
+}
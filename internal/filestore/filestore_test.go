@@ -0,0 +1,105 @@
+package filestore
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+	"github.com/simondrake/copy-paste-notes/internal/notes/notestest"
+)
+
+func newClient(t *testing.T) *Client {
+	t.Helper()
+
+	c, err := New(filepath.Join(t.TempDir(), "notes.json"))
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestConformance(t *testing.T) {
+	notestest.Conformance(t, func(t *testing.T) notes.NoteReaderWriter {
+		return newClient(t)
+	})
+}
+
+func TestNew(t *testing.T) {
+	t.Run("should create the file if it doesn't exist", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "notes.json")
+
+		_, err := New(file)
+		require.NoError(t, err)
+		assert.FileExists(t, file)
+	})
+
+	t.Run("should return an error for a file that isn't a notes document", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "notes.json")
+		require.NoError(t, os.WriteFile(file, []byte(`{"version": 99}`), 0o600))
+
+		_, err := New(file)
+		assert.ErrorIs(t, err, ErrUnknownVersion)
+	})
+
+	t.Run("should convert the local timestamps of version 1 files", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "notes.json")
+		require.NoError(t, os.WriteFile(file, []byte(`{"version": 1, "nextId": 2, "notes": [{
+			"id": 1, "title": "t", "description": "d", "createTimestamp": "2023-09-01 10:00:00",
+			"revisions": [{"noteId": 1, "revision": 1, "createTimestamp": "2023-09-02 10:00:00", "title": "t", "description": "old"}]
+		}]}`), 0o600))
+
+		c, err := New(file)
+		require.NoError(t, err)
+
+		n, err := c.GetNoteByID(1)
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2023, 9, 1, 10, 0, 0, 0, time.Local).UTC(), n.CreateTimestamp)
+
+		rs, err := c.ListNoteRevisions(1)
+		require.NoError(t, err)
+		require.Len(t, rs, 1)
+		assert.Equal(t, time.Date(2023, 9, 2, 10, 0, 0, 0, time.Local).UTC(), rs[0].CreateTimestamp)
+
+		require.NoError(t, c.MarkNoteUsed(1))
+
+		b, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Contains(t, string(b), `"version": 2`)
+		assert.Contains(t, string(b), notes.FormatTimestamp(n.CreateTimestamp))
+	})
+
+	t.Run("should return an error for invalid timestamps", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "notes.json")
+		require.NoError(t, os.WriteFile(file, []byte(`{"version": 2, "nextId": 2, "notes": [{"id": 1, "title": "t", "description": "d", "createTimestamp": "yesterday"}]}`), 0o600))
+
+		_, err := New(file)
+		assert.Error(t, err)
+	})
+}
+
+func TestLock(t *testing.T) {
+	c := newClient(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			_, err := c.InsertNote(notes.Note{Title: time.Duration(i).String(), Description: "d"})
+			assert.NoError(t, err)
+		}(i)
+	}
+
+	wg.Wait()
+
+	ns, err := c.ListNotes()
+	assert.NoError(t, err)
+	assert.Len(t, ns, 20)
+}
@@ -0,0 +1,89 @@
+package filestore
+
+import (
+	"fmt"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+// ImportNotes adds the notes to the document, which is written once, so either all of them
+// are imported or none are. IDs are ignored and notes without a create timestamp are given
+// the current time, while their other timestamps and use count are copied across. Conflicts
+// with notes outside the trash are handled according to onConflict.
+func (c *Client) ImportNotes(ns []notes.Note, onConflict notes.ConflictPolicy) (notes.ImportResult, error) {
+	var res notes.ImportResult
+
+	if err := onConflict.Validate(); err != nil {
+		return res, err
+	}
+
+	err := c.update(func(d *document) error {
+		created := notes.Now()
+
+		for _, n := range ns {
+			tags, err := notes.NormaliseTags(n.Tags)
+			if err != nil {
+				return fmt.Errorf("note %q: %w", n.Title, err)
+			}
+
+			if n.CreateTimestamp.IsZero() {
+				n.CreateTimestamp = created
+			}
+
+			existing := d.byTitle(n.Title)
+
+			switch {
+			case existing == nil:
+				res.Inserted++
+			case onConflict == notes.ConflictSkip:
+				res.Skipped++
+				continue
+			case onConflict == notes.ConflictOverwrite:
+				existing.saveRevision()
+				existing.Description = n.Description
+				existing.CreateTimestamp = timestamp(n.CreateTimestamp)
+				existing.UpdateTimestamp = now()
+				existing.LastUsedTimestamp = timestamp(n.LastUsedTimestamp)
+				existing.UseCount = n.UseCount
+				existing.Tags = tags
+
+				res.Overwritten++
+				continue
+			case onConflict == notes.ConflictRename:
+				for i := 2; d.byTitle(n.Title) != nil; i++ {
+					n.Title = fmt.Sprintf("%s (%d)", existing.Title, i)
+				}
+
+				res.Renamed++
+			}
+
+			id, err := d.insert(n, tags)
+			if err != nil {
+				return err
+			}
+
+			r := d.find(id)
+			r.UpdateTimestamp = timestamp(n.UpdateTimestamp)
+			r.LastUsedTimestamp = timestamp(n.LastUsedTimestamp)
+			r.UseCount = n.UseCount
+		}
+
+		return nil
+	})
+	if err != nil {
+		return notes.ImportResult{}, err
+	}
+
+	return res, nil
+}
+
+// byTitle returns the note with the given title that isn't in the trash.
+func (d *document) byTitle(title string) *record {
+	for i := range d.Notes {
+		if d.Notes[i].Title == title && d.Notes[i].DeleteTimestamp == "" {
+			return &d.Notes[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+var ErrLocked = errors.New("timed out waiting for the lock on the notes file")
+
+const (
+	lockTimeout  = 5 * time.Second
+	lockInterval = 10 * time.Millisecond
+	// staleLockAge is how old a lock file must be before it's assumed to have been left
+	// behind by a process that died while holding it. Writes take milliseconds, so this is
+	// very conservative.
+	staleLockAge = 30 * time.Second
+)
+
+// lock takes an exclusive lock on path by creating path.lock, which works the same way on
+// every platform and file system. The returned function releases the lock.
+func lock(path string) (func(), error) {
+	name := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+
+			return func() { os.Remove(name) }, nil
+		}
+
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+
+		if fi, err := os.Stat(name); err == nil && time.Since(fi.ModTime()) > staleLockAge {
+			os.Remove(name)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: remove %s if no other copy-paste-notes process is running", ErrLocked, name)
+		}
+
+		time.Sleep(lockInterval)
+	}
+}
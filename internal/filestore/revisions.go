@@ -0,0 +1,136 @@
+package filestore
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+func (c *Client) ListNoteRevisions(id int) ([]notes.Revision, error) {
+	d, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]notes.Revision, 0)
+	if r := d.find(id); r != nil {
+		for _, rev := range r.Revisions {
+			out = append(out, rev.revision())
+		}
+	}
+
+	return out, nil
+}
+
+func (c *Client) GetNoteRevision(id int, revision int) (*notes.Revision, error) {
+	d, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+
+	if r := d.find(id); r != nil {
+		for _, rev := range r.Revisions {
+			if rev.Revision == revision {
+				out := rev.revision()
+				return &out, nil
+			}
+		}
+	}
+
+	return nil, notes.ErrNotFound
+}
+
+// RestoreNoteRevision updates a note with the title and description of one of its revisions.
+// The current version is saved as a new revision first, so the restore can be undone.
+func (c *Client) RestoreNoteRevision(id int, revision int) (int64, error) {
+	rev, err := c.GetNoteRevision(id, revision)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.UpdateNote(id, notes.Note{Title: rev.Title, Description: rev.Description})
+}
+
+func (c *Client) ListTrashedNotes() ([]notes.Note, error) {
+	d, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]notes.Note, 0)
+	for _, r := range d.Notes {
+		if r.DeleteTimestamp != "" {
+			out = append(out, r.note())
+		}
+	}
+
+	return out, nil
+}
+
+func (c *Client) RestoreNote(id int) error {
+	return c.update(func(d *document) error {
+		r := d.find(id)
+		if r == nil || r.DeleteTimestamp == "" {
+			return ErrRestoreFailed
+		}
+
+		if d.titleTaken(r.Title, id) {
+			return fmt.Errorf("%w: %q", notes.ErrDuplicateTitle, r.Title)
+		}
+
+		r.DeleteTimestamp = ""
+
+		return nil
+	})
+}
+
+// PurgeNote permanently deletes a note, whether or not it is in the trash, along with its
+// tags and revisions.
+func (c *Client) PurgeNote(id int) error {
+	return c.update(func(d *document) error {
+		if d.purge(func(r record) bool { return r.ID == id }) == 0 {
+			return ErrDeleteFailed
+		}
+
+		return nil
+	})
+}
+
+// EmptyTrash permanently deletes the notes that were moved to the trash before the given
+// time, or all of them if it is zero. It returns the number of notes deleted.
+func (c *Client) EmptyTrash(before time.Time) (int64, error) {
+	var purged int64
+
+	err := c.update(func(d *document) error {
+		purged = d.purge(func(r record) bool {
+			if r.DeleteTimestamp == "" {
+				return false
+			}
+
+			return before.IsZero() || r.DeleteTimestamp < notes.FormatTimestamp(before)
+		})
+
+		return nil
+	})
+
+	return purged, err
+}
+
+func (d *document) purge(match func(record) bool) int64 {
+	var purged int64
+
+	kept := d.Notes[:0]
+	for _, r := range d.Notes {
+		if match(r) {
+			purged++
+			continue
+		}
+
+		kept = append(kept, r)
+	}
+
+	d.Notes = kept
+
+	return purged
+}
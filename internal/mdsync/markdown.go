@@ -0,0 +1,162 @@
+package mdsync
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+var (
+	ErrInvalidFrontMatter = errors.New("invalid front matter")
+	ErrMissingTitle       = errors.New("the note must have a title")
+	ErrMissingDescription = errors.New("the note must have a description")
+)
+
+const frontMatterDelimiter = "---\n"
+
+type frontMatter struct {
+	ID      int      `yaml:"id,omitempty"`
+	Title   string   `yaml:"title"`
+	Created string   `yaml:"created,omitempty"`
+	Tags    []string `yaml:"tags,omitempty,flow"`
+}
+
+// Format renders a note as Markdown, with the ID, title, created timestamp and tags in a
+// YAML front matter header followed by the description.
+func Format(n notes.Note) []byte {
+	var b bytes.Buffer
+
+	b.WriteString(frontMatterDelimiter)
+
+	e := yaml.NewEncoder(&b)
+	e.SetIndent(2)
+	fm := frontMatter{ID: n.ID, Title: n.Title, Tags: n.Tags}
+	if !n.CreateTimestamp.IsZero() {
+		fm.Created = notes.FormatTimestamp(n.CreateTimestamp)
+	}
+
+	// Encoding a struct of strings and ints can't fail
+	_ = e.Encode(fm)
+	_ = e.Close()
+
+	b.WriteString(frontMatterDelimiter)
+	b.WriteString(n.Description)
+	b.WriteString("\n")
+
+	return b.Bytes()
+}
+
+// Parse reads a note in the format written by Format. The tags of the returned note are
+// never nil, so that removing them all from the file removes them from the note.
+func Parse(b []byte) (notes.Note, error) {
+	s := strings.ReplaceAll(string(b), "\r\n", "\n")
+
+	if !strings.HasPrefix(s, frontMatterDelimiter) {
+		return notes.Note{}, fmt.Errorf("%w: the file must start with %q", ErrInvalidFrontMatter, strings.TrimSpace(frontMatterDelimiter))
+	}
+
+	header, body, ok := strings.Cut(s[len(frontMatterDelimiter):], "\n"+frontMatterDelimiter)
+	if !ok {
+		return notes.Note{}, fmt.Errorf("%w: missing closing %q", ErrInvalidFrontMatter, strings.TrimSpace(frontMatterDelimiter))
+	}
+
+	var fm frontMatter
+
+	d := yaml.NewDecoder(strings.NewReader(header))
+	d.KnownFields(true)
+	if err := d.Decode(&fm); err != nil {
+		return notes.Note{}, fmt.Errorf("%w: %v", ErrInvalidFrontMatter, err)
+	}
+
+	n := notes.Note{
+		ID:          fm.ID,
+		Title:       strings.TrimSpace(fm.Title),
+		Description: strings.TrimSuffix(body, "\n"),
+		Tags:        fm.Tags,
+	}
+
+	if fm.Created != "" {
+		created, err := notes.ParseTimestamp(fm.Created)
+		if err != nil {
+			return notes.Note{}, fmt.Errorf("%w: created: %v", ErrInvalidFrontMatter, err)
+		}
+
+		n.CreateTimestamp = created
+	}
+
+	if n.Tags == nil {
+		n.Tags = []string{}
+	}
+
+	if n.Title == "" {
+		return n, ErrMissingTitle
+	}
+
+	if strings.TrimSpace(n.Description) == "" {
+		return n, ErrMissingDescription
+	}
+
+	return n, nil
+}
+
+// Hash returns a hash of the parts of a note that are synced, i.e. the title, description
+// and tags.
+func Hash(n notes.Note) string {
+	seen := make(map[string]bool, len(n.Tags))
+	tags := make([]string, 0, len(n.Tags))
+	for _, t := range n.Tags {
+		if !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	sort.Strings(tags)
+
+	h := sha256.New()
+	for _, s := range []string{n.Title, n.Description, strings.Join(tags, ",")} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// slug turns a title into a file name, without the extension.
+func slug(title string) string {
+	var b strings.Builder
+
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+
+		if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+
+	s := strings.TrimSuffix(b.String(), "-")
+
+	if r := []rune(s); len(r) > 60 {
+		s = strings.TrimSuffix(string(r[:60]), "-")
+	}
+
+	if s == "" {
+		return "note"
+	}
+
+	return s
+}
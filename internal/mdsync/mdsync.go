@@ -0,0 +1,448 @@
+// Package mdsync mirrors notes to a directory of Markdown files, one per note, and syncs
+// changes made on either side.
+package mdsync
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+// StateFile is the name of the file, inside the synced directory, that records the hash of
+// every note as of the last sync. It's used to tell which side changed a note.
+const StateFile = ".cpn-sync.json"
+
+// ConflictSuffix is added to the name of a note's file to get the name of the file that the
+// database version is written to when both sides changed it.
+const ConflictSuffix = ".conflict"
+
+// Store is the subset of notes.Client needed to sync.
+type Store interface {
+	List() ([]notes.Note, error)
+	Create(notes.Note) (int, error)
+	Update(int, notes.Note) (int64, error)
+	Delete(int) error
+}
+
+type state struct {
+	LastSync time.Time     `json:"lastSync"`
+	Notes    map[int]entry `json:"notes"`
+}
+
+type entry struct {
+	File string `json:"file"`
+	Hash string `json:"hash"`
+	// Conflict is set while the note has a conflict file waiting to be resolved.
+	Conflict bool `json:"conflict,omitempty"`
+}
+
+// Conflict is a note that was changed on both sides since the last sync.
+type Conflict struct {
+	ID     int
+	Title  string
+	File   string
+	Reason string
+}
+
+// Report describes the changes made by a sync.
+type Report struct {
+	FilesWritten int
+	FilesRemoved int
+	Inserted     int
+	Updated      int
+	Deleted      int
+	Conflicts    []Conflict
+	// Errors are problems with individual notes or files, which are skipped and retried on
+	// the next sync.
+	Errors []error
+}
+
+type file struct {
+	name string
+	note notes.Note
+}
+
+type syncer struct {
+	store  Store
+	dir    string
+	state  *state
+	report *Report
+	// used holds the names of the Markdown files that exist or are about to be written.
+	used map[string]bool
+}
+
+// Dir syncs the notes in the store with the Markdown files in dir, creating it if needed.
+//
+// Notes that only changed on one side since the last sync are copied to the other side,
+// including deletions. If both sides changed a note, the file is left alone, the database
+// version is written next to it with the ConflictSuffix and the conflict is reported. The
+// conflict is resolved, in favour of the file, by removing the conflict file.
+func Dir(store Store, dir string) (*Report, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	st, err := loadState(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ns, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &syncer{store: store, dir: dir, state: st, report: &Report{}, used: make(map[string]bool)}
+
+	db := make(map[int]notes.Note, len(ns))
+	for _, n := range ns {
+		db[n.ID] = n
+	}
+
+	files, added, broken, err := s.readFiles(db)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[int]bool)
+	for id := range db {
+		ids[id] = true
+	}
+	for id := range files {
+		ids[id] = true
+	}
+	for id := range st.Notes {
+		ids[id] = true
+	}
+
+	sorted := make([]int, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Ints(sorted)
+
+	for _, id := range sorted {
+		e, known := st.Notes[id]
+		d, inDB := db[id]
+		f, inFile := files[id]
+
+		if known && !inFile && broken[e.File] {
+			// The file couldn't be read, which has already been reported, so don't treat it
+			// as deleted
+			continue
+		}
+
+		if err := s.syncNote(id, e, known, d, inDB, f, inFile); err != nil {
+			s.report.Errors = append(s.report.Errors, fmt.Errorf("note %d: %w", id, err))
+		}
+	}
+
+	for _, f := range added {
+		if err := s.insert(f); err != nil {
+			s.report.Errors = append(s.report.Errors, fmt.Errorf("%s: %w", f.name, err))
+		}
+	}
+
+	st.LastSync = time.Now().UTC()
+
+	if err := saveState(dir, st); err != nil {
+		return nil, err
+	}
+
+	return s.report, nil
+}
+
+// readFiles parses the Markdown files in the directory, returning those that belong to a
+// known note by ID, those that are new and the names of any that couldn't be parsed.
+func (s *syncer) readFiles(db map[int]notes.Note) (map[int]file, []file, map[string]bool, error) {
+	des, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	files := make(map[int]file)
+	added := make([]file, 0)
+	broken := make(map[string]bool)
+
+	for _, de := range des {
+		if de.IsDir() || filepath.Ext(de.Name()) != ".md" {
+			continue
+		}
+
+		s.used[de.Name()] = true
+
+		b, err := os.ReadFile(filepath.Join(s.dir, de.Name()))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		n, err := Parse(b)
+		if err != nil {
+			broken[de.Name()] = true
+			s.report.Errors = append(s.report.Errors, fmt.Errorf("%s: %w", de.Name(), err))
+			continue
+		}
+
+		f := file{name: de.Name(), note: n}
+
+		_, inDB := db[n.ID]
+		_, known := s.state.Notes[n.ID]
+
+		if n.ID == 0 || (!inDB && !known) {
+			added = append(added, f)
+			continue
+		}
+
+		if other, ok := files[n.ID]; ok {
+			broken[de.Name()] = true
+			s.report.Errors = append(s.report.Errors, fmt.Errorf("%s: id %d is already used by %s", de.Name(), n.ID, other.name))
+			continue
+		}
+
+		files[n.ID] = f
+	}
+
+	return files, added, broken, nil
+}
+
+func (s *syncer) syncNote(id int, e entry, known bool, d notes.Note, inDB bool, f file, inFile bool) error {
+	if !known {
+		if inFile {
+			// Without a previous sync to compare against, the file and note both count as
+			// changed
+			return s.bothChanged(id, entry{File: f.name}, d, inDB, f, inFile)
+		}
+
+		return s.writeFile(id, s.fileName(d), d)
+	}
+
+	dbChanged := !inDB || Hash(d) != e.Hash
+	fileChanged := !inFile || Hash(f.note) != e.Hash
+
+	if e.Conflict {
+		if _, err := os.Stat(s.conflictPath(e.File)); err == nil {
+			if inDB {
+				if err := os.WriteFile(s.conflictPath(e.File), Format(d), 0o644); err != nil {
+					return err
+				}
+			}
+
+			title := d.Title
+			if !inDB {
+				title = f.note.Title
+			}
+
+			s.conflict(id, title, e.File, "unresolved, remove the conflict file once the note file is correct")
+
+			return nil
+		}
+
+		// The conflict file was removed, so the file wins
+		dbChanged = false
+	}
+
+	switch {
+	case dbChanged && fileChanged:
+		return s.bothChanged(id, e, d, inDB, f, inFile)
+	case dbChanged && !inDB:
+		if err := os.Remove(filepath.Join(s.dir, e.File)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		delete(s.state.Notes, id)
+		s.report.FilesRemoved++
+	case dbChanged:
+		return s.writeFile(id, f.name, d)
+	case fileChanged && !inFile:
+		if inDB {
+			if err := s.store.Delete(id); err != nil {
+				return err
+			}
+
+			s.report.Deleted++
+		}
+
+		delete(s.state.Notes, id)
+	case fileChanged:
+		if !inDB {
+			// The conflict file was removed after the note was deleted, so it comes back as
+			// a new note
+			delete(s.state.Notes, id)
+			return s.insert(f)
+		}
+
+		ra, err := s.store.Update(id, notes.Note{Title: f.note.Title, Description: f.note.Description, Tags: f.note.Tags})
+		if err != nil {
+			return err
+		}
+
+		if ra == 0 {
+			return fmt.Errorf("note %d was not updated", id)
+		}
+
+		s.state.Notes[id] = entry{File: f.name, Hash: Hash(f.note)}
+		s.report.Updated++
+	default:
+		// Unchanged, but the file may have been renamed
+		s.state.Notes[id] = entry{File: f.name, Hash: e.Hash}
+	}
+
+	return nil
+}
+
+// bothChanged handles a note that was changed in both the database and the file.
+func (s *syncer) bothChanged(id int, e entry, d notes.Note, inDB bool, f file, inFile bool) error {
+	switch {
+	case !inDB && !inFile:
+		delete(s.state.Notes, id)
+	case inDB && inFile && Hash(d) == Hash(f.note):
+		s.state.Notes[id] = entry{File: f.name, Hash: Hash(d)}
+	case !inDB:
+		s.conflict(id, f.note.Title, f.name, "deleted in the database but changed in the file")
+	default:
+		if err := os.WriteFile(s.conflictPath(e.File), Format(d), 0o644); err != nil {
+			return err
+		}
+
+		e.Conflict = true
+		s.state.Notes[id] = e
+
+		reason := "changed in both the database and the file"
+		if !inFile {
+			reason = "changed in the database but the file was deleted"
+		}
+
+		s.conflict(id, d.Title, e.File, reason)
+	}
+
+	return nil
+}
+
+// insert adds a new file's note to the database and rewrites the file with its new ID.
+func (s *syncer) insert(f file) error {
+	n := f.note
+
+	if n.CreateTimestamp.IsZero() {
+		n.CreateTimestamp = notes.Now()
+	}
+
+	id, err := s.store.Create(notes.Note{Title: n.Title, Description: n.Description, CreateTimestamp: n.CreateTimestamp, Tags: n.Tags})
+	if err != nil {
+		return err
+	}
+
+	s.report.Inserted++
+
+	n.ID = id
+
+	return s.writeFile(id, f.name, n)
+}
+
+func (s *syncer) writeFile(id int, name string, n notes.Note) error {
+	if err := os.WriteFile(filepath.Join(s.dir, name), Format(n), 0o644); err != nil {
+		return err
+	}
+
+	s.used[name] = true
+	s.state.Notes[id] = entry{File: name, Hash: Hash(n)}
+	s.report.FilesWritten++
+
+	return nil
+}
+
+// fileName picks an unused file name for a note that doesn't have a file yet.
+func (s *syncer) fileName(n notes.Note) string {
+	name := slug(n.Title) + ".md"
+
+	taken := s.used[name]
+	for _, e := range s.state.Notes {
+		taken = taken || e.File == name
+	}
+
+	if taken {
+		name = slug(n.Title) + "-" + strconv.Itoa(n.ID) + ".md"
+	}
+
+	return name
+}
+
+func (s *syncer) conflictPath(name string) string {
+	return filepath.Join(s.dir, name+ConflictSuffix)
+}
+
+func (s *syncer) conflict(id int, title string, name string, reason string) {
+	s.report.Conflicts = append(s.report.Conflicts, Conflict{ID: id, Title: title, File: name, Reason: reason})
+}
+
+func loadState(dir string) (*state, error) {
+	st := &state{Notes: make(map[int]entry)}
+
+	b, err := os.ReadFile(filepath.Join(dir, StateFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return st, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", StateFile, err)
+	}
+
+	if st.Notes == nil {
+		st.Notes = make(map[int]entry)
+	}
+
+	return st, nil
+}
+
+// saveState writes the state to a temporary file and renames it, so an interrupted sync
+// can't leave a half written state behind.
+func saveState(dir string, st *state) error {
+	b, err := json.MarshalIndent(st, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, StateFile+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(dir, StateFile))
+}
+
+// String summarises the report on a single line.
+func (r *Report) String() string {
+	parts := []string{
+		fmt.Sprintf("%d files written", r.FilesWritten),
+		fmt.Sprintf("%d files removed", r.FilesRemoved),
+		fmt.Sprintf("%d notes inserted", r.Inserted),
+		fmt.Sprintf("%d notes updated", r.Updated),
+		fmt.Sprintf("%d notes deleted", r.Deleted),
+		fmt.Sprintf("%d conflicts", len(r.Conflicts)),
+	}
+
+	return strings.Join(parts, ", ")
+}
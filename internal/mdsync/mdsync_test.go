@@ -0,0 +1,224 @@
+package mdsync
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+type fakeStore struct {
+	notes  map[int]notes.Note
+	nextID int
+}
+
+func (s *fakeStore) List() ([]notes.Note, error) {
+	out := make([]notes.Note, 0, len(s.notes))
+	for _, n := range s.notes {
+		out = append(out, n)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+
+	return out, nil
+}
+
+func (s *fakeStore) Create(n notes.Note) (int, error) {
+	s.nextID++
+	n.ID = s.nextID
+	s.notes[n.ID] = n
+
+	return n.ID, nil
+}
+
+func (s *fakeStore) Update(id int, n notes.Note) (int64, error) {
+	cur, ok := s.notes[id]
+	if !ok {
+		return 0, nil
+	}
+
+	if n.Title != "" {
+		cur.Title = n.Title
+	}
+
+	if n.Description != "" {
+		cur.Description = n.Description
+	}
+
+	if n.Tags != nil {
+		cur.Tags = n.Tags
+	}
+
+	s.notes[id] = cur
+
+	return 1, nil
+}
+
+func (s *fakeStore) Delete(id int) error {
+	if _, ok := s.notes[id]; !ok {
+		return notes.ErrNotFound
+	}
+
+	delete(s.notes, id)
+
+	return nil
+}
+
+func readNote(t *testing.T, path string) notes.Note {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	n, err := Parse(b)
+	require.NoError(t, err)
+
+	return n
+}
+
+func TestFormatParse(t *testing.T) {
+	n := notes.Note{ID: 3, Title: "kubectl: logs", Description: "kubectl logs <pod>\n\n--tail 10\n", CreateTimestamp: time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC), Tags: []string{"k8s"}}
+
+	got, err := Parse(Format(n))
+	require.NoError(t, err)
+	assert.Equal(t, n, got)
+
+	_, err = Parse([]byte("title: x\n"))
+	assert.ErrorIs(t, err, ErrInvalidFrontMatter)
+
+	_, err = Parse([]byte("---\ntitle: x\ncolour: red\n---\ny\n"))
+	assert.ErrorIs(t, err, ErrInvalidFrontMatter)
+
+	_, err = Parse([]byte("---\ntitle: x\ncreated: yesterday\n---\ny\n"))
+	assert.ErrorIs(t, err, ErrInvalidFrontMatter)
+
+	_, err = Parse([]byte("---\ntitle: x\n---\n\n"))
+	assert.ErrorIs(t, err, ErrMissingDescription)
+}
+
+func TestSlug(t *testing.T) {
+	assert.Equal(t, "kubectl-get-pods-n-ns", slug("kubectl get pods -n <ns>"))
+	assert.Equal(t, "note", slug("!!!"))
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+
+	store := &fakeStore{notes: map[int]notes.Note{
+		1: {ID: 1, Title: "Git log", Description: "git log --oneline", CreateTimestamp: time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC), Tags: []string{}},
+		2: {ID: 2, Title: "psql", Description: "psql -h localhost", CreateTimestamp: time.Date(2023, 9, 2, 10, 0, 0, 0, time.UTC), Tags: []string{"db"}},
+	}, nextID: 2}
+
+	gitFile := filepath.Join(dir, "git-log.md")
+	psqlFile := filepath.Join(dir, "psql.md")
+
+	t.Run("should write a file for every note", func(t *testing.T) {
+		r, err := Dir(store, dir)
+		require.NoError(t, err)
+		assert.Empty(t, r.Errors)
+		assert.Equal(t, 2, r.FilesWritten)
+
+		assert.Equal(t, store.notes[1], readNote(t, gitFile))
+		assert.Equal(t, store.notes[2], readNote(t, psqlFile))
+	})
+
+	t.Run("should do nothing when nothing has changed", func(t *testing.T) {
+		r, err := Dir(store, dir)
+		require.NoError(t, err)
+		assert.Equal(t, &Report{}, r)
+	})
+
+	t.Run("should apply changes made to files", func(t *testing.T) {
+		n := readNote(t, gitFile)
+		n.Description = "git log --oneline --graph"
+		n.Tags = []string{"git"}
+		require.NoError(t, os.WriteFile(gitFile, Format(n), 0o644))
+
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "new.md"), []byte("---\ntitle: New\n---\necho new\n"), 0o644))
+
+		r, err := Dir(store, dir)
+		require.NoError(t, err)
+		assert.Empty(t, r.Errors)
+		assert.Equal(t, 1, r.Updated)
+		assert.Equal(t, 1, r.Inserted)
+
+		assert.Equal(t, "git log --oneline --graph", store.notes[1].Description)
+		assert.Equal(t, []string{"git"}, store.notes[1].Tags)
+
+		assert.Equal(t, "echo new", store.notes[3].Description)
+		assert.Equal(t, 3, readNote(t, filepath.Join(dir, "new.md")).ID)
+	})
+
+	t.Run("should apply changes made to the database", func(t *testing.T) {
+		store.notes[2] = notes.Note{ID: 2, Title: "psql", Description: "psql -h db", CreateTimestamp: time.Date(2023, 9, 2, 10, 0, 0, 0, time.UTC), Tags: []string{"db"}}
+		require.NoError(t, store.Delete(3))
+
+		r, err := Dir(store, dir)
+		require.NoError(t, err)
+		assert.Empty(t, r.Errors)
+		assert.Equal(t, 1, r.FilesWritten)
+		assert.Equal(t, 1, r.FilesRemoved)
+
+		assert.Equal(t, "psql -h db", readNote(t, psqlFile).Description)
+		assert.NoFileExists(t, filepath.Join(dir, "new.md"))
+	})
+
+	t.Run("should write a conflict file when both sides changed", func(t *testing.T) {
+		n := readNote(t, psqlFile)
+		n.Description = "psql -h file"
+		require.NoError(t, os.WriteFile(psqlFile, Format(n), 0o644))
+
+		store.notes[2] = notes.Note{ID: 2, Title: "psql", Description: "psql -h database", CreateTimestamp: time.Date(2023, 9, 2, 10, 0, 0, 0, time.UTC), Tags: []string{"db"}}
+
+		for i := 0; i < 2; i++ {
+			r, err := Dir(store, dir)
+			require.NoError(t, err)
+			require.Len(t, r.Conflicts, 1)
+			assert.Equal(t, 2, r.Conflicts[0].ID)
+			assert.Equal(t, "psql.md", r.Conflicts[0].File)
+
+			assert.Equal(t, "psql -h file", readNote(t, psqlFile).Description)
+			assert.Equal(t, "psql -h database", readNote(t, psqlFile+ConflictSuffix).Description)
+			assert.Equal(t, "psql -h database", store.notes[2].Description)
+		}
+	})
+
+	t.Run("should use the file once the conflict file is removed", func(t *testing.T) {
+		require.NoError(t, os.Remove(psqlFile+ConflictSuffix))
+
+		r, err := Dir(store, dir)
+		require.NoError(t, err)
+		assert.Empty(t, r.Conflicts)
+		assert.Equal(t, 1, r.Updated)
+		assert.Equal(t, "psql -h file", store.notes[2].Description)
+
+		r, err = Dir(store, dir)
+		require.NoError(t, err)
+		assert.Equal(t, &Report{}, r)
+	})
+
+	t.Run("should delete notes whose file was removed", func(t *testing.T) {
+		require.NoError(t, os.Remove(gitFile))
+
+		r, err := Dir(store, dir)
+		require.NoError(t, err)
+		assert.Equal(t, 1, r.Deleted)
+		assert.NotContains(t, store.notes, 1)
+	})
+
+	t.Run("should report files that can't be parsed without deleting their note", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(psqlFile, []byte("not front matter\n"), 0o644))
+
+		r, err := Dir(store, dir)
+		require.NoError(t, err)
+		assert.Len(t, r.Errors, 1)
+		assert.Zero(t, r.Deleted)
+		assert.Contains(t, store.notes, 2)
+	})
+}
@@ -0,0 +1,3 @@
+-- The full-text search index is managed by the store rather than by migrations, see
+-- internal/sqlite/fts.go, so there's nothing to undo.
+SELECT 1;
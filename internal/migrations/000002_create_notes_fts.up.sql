@@ -0,0 +1,3 @@
+-- The full-text search index used to be created here, which failed when SQLite didn't have
+-- FTS5. It's now created by the store when FTS5 is available, see internal/sqlite/fts.go.
+SELECT 1;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS "note_revisions" (
+  "id" INTEGER NOT NULL PRIMARY KEY,
+  "note_id" INTEGER NOT NULL REFERENCES "notes" ("id") ON DELETE CASCADE,
+  "revision" INTEGER NOT NULL,
+  "create_timestamp" TEXT NOT NULL,
+  "title" TEXT NOT NULL,
+  "description" TEXT NOT NULL,
+  UNIQUE ("note_id", "revision")
+  );
@@ -0,0 +1 @@
+ALTER TABLE "notes" DROP COLUMN "deleted_at";
@@ -0,0 +1 @@
+ALTER TABLE "notes" ADD COLUMN "deleted_at" TEXT;
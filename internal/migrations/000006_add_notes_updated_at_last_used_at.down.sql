@@ -0,0 +1,6 @@
+ALTER TABLE "notes" DROP COLUMN "last_used_at";
+ALTER TABLE "notes" DROP COLUMN "updated_at";
+
+UPDATE "notes" SET "create_timestamp" = strftime('%Y-%m-%d %H:%M:%S', "create_timestamp", 'localtime') WHERE "create_timestamp" GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9]Z';
+UPDATE "notes" SET "deleted_at" = strftime('%Y-%m-%d %H:%M:%S', "deleted_at", 'localtime') WHERE "deleted_at" GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9]Z';
+UPDATE "note_revisions" SET "create_timestamp" = strftime('%Y-%m-%d %H:%M:%S', "create_timestamp", 'localtime') WHERE "create_timestamp" GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9]T[0-9][0-9]:[0-9][0-9]:[0-9][0-9]Z';
@@ -0,0 +1,10 @@
+-- Timestamps were stored in local time with no zone, convert them to RFC3339 in UTC
+UPDATE "notes" SET "create_timestamp" = strftime('%Y-%m-%dT%H:%M:%SZ', "create_timestamp", 'utc') WHERE "create_timestamp" GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9] [0-9][0-9]:[0-9][0-9]:[0-9][0-9]';
+UPDATE "notes" SET "deleted_at" = strftime('%Y-%m-%dT%H:%M:%SZ', "deleted_at", 'utc') WHERE "deleted_at" GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9] [0-9][0-9]:[0-9][0-9]:[0-9][0-9]';
+UPDATE "note_revisions" SET "create_timestamp" = strftime('%Y-%m-%dT%H:%M:%SZ', "create_timestamp", 'utc') WHERE "create_timestamp" GLOB '[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9] [0-9][0-9]:[0-9][0-9]:[0-9][0-9]';
+
+ALTER TABLE "notes" ADD COLUMN "updated_at" TEXT;
+ALTER TABLE "notes" ADD COLUMN "last_used_at" TEXT;
+
+-- A revision is saved whenever a note is updated, so the latest one is when it last changed
+UPDATE "notes" SET "updated_at" = (SELECT MAX("create_timestamp") FROM "note_revisions" WHERE "note_id" = "notes"."id");
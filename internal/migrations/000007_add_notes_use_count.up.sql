@@ -0,0 +1,4 @@
+ALTER TABLE "notes" ADD COLUMN "use_count" INTEGER NOT NULL DEFAULT 0;
+
+-- Notes were only marked as used when they were copied, so any that have been were copied at least once
+UPDATE "notes" SET "use_count" = 1 WHERE "last_used_at" IS NOT NULL;
@@ -0,0 +1,25 @@
+-- Titles must be unique again, so trashed notes that share their title with a live note or an
+-- older trashed note have their ID appended to it
+UPDATE "notes" SET "title" = "title" || ' (' || "id" || ')'
+  WHERE "deleted_at" IS NOT NULL AND EXISTS (
+    SELECT 1 FROM "notes" AS "other" WHERE "other"."title" = "notes"."title" AND "other"."id" <> "notes"."id"
+      AND ("other"."deleted_at" IS NULL OR "other"."id" < "notes"."id")
+  );
+
+CREATE TABLE "notes_new" (
+  "id" INTEGER NOT NULL PRIMARY KEY,
+  "create_timestamp" TEXT,
+  "title" TEXT NOT NULL UNIQUE,
+  "description" TEXT NOT NULL,
+  "deleted_at" TEXT,
+  "updated_at" TEXT,
+  "last_used_at" TEXT,
+  "use_count" INTEGER NOT NULL DEFAULT 0
+  );
+
+INSERT INTO "notes_new" ("id", "create_timestamp", "title", "description", "deleted_at", "updated_at", "last_used_at", "use_count")
+  SELECT "id", "create_timestamp", "title", "description", "deleted_at", "updated_at", "last_used_at", "use_count" FROM "notes";
+
+DROP INDEX "notes_title_live";
+DROP TABLE "notes";
+ALTER TABLE "notes_new" RENAME TO "notes";
@@ -0,0 +1,21 @@
+-- Titles only need to be unique among the notes that aren't in the trash, so the title of a
+-- trashed note can be reused. SQLite can't drop the column's UNIQUE constraint, so the table
+-- is rebuilt without it.
+CREATE TABLE "notes_new" (
+  "id" INTEGER NOT NULL PRIMARY KEY,
+  "create_timestamp" TEXT,
+  "title" TEXT NOT NULL,
+  "description" TEXT NOT NULL,
+  "deleted_at" TEXT,
+  "updated_at" TEXT,
+  "last_used_at" TEXT,
+  "use_count" INTEGER NOT NULL DEFAULT 0
+  );
+
+INSERT INTO "notes_new" ("id", "create_timestamp", "title", "description", "deleted_at", "updated_at", "last_used_at", "use_count")
+  SELECT "id", "create_timestamp", "title", "description", "deleted_at", "updated_at", "last_used_at", "use_count" FROM "notes";
+
+DROP TABLE "notes";
+ALTER TABLE "notes_new" RENAME TO "notes";
+
+CREATE UNIQUE INDEX "notes_title_live" ON "notes" ("title") WHERE "deleted_at" IS NULL;
@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations for the sqlite database, so that they are
+// available to installed binaries and not just source checkouts.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
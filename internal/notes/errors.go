@@ -0,0 +1,58 @@
+package notes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound       = errors.New("note not found")
+	ErrDuplicateTitle = errors.New("a note with that title already exists")
+	ErrInvalidNote    = errors.New("invalid note")
+)
+
+// Error is returned by Client for missing notes, duplicate titles and invalid notes. Its
+// message is suitable for showing to users. errors.Is matches its Kind, which is one of
+// ErrNotFound, ErrDuplicateTitle or ErrInvalidNote, as well as the error it wraps.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func invalid(err error, format string, args ...any) error {
+	return &Error{Kind: ErrInvalidNote, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// notFound turns the errors backends return for missing notes, sql.ErrNoRows or anything
+// wrapping ErrNotFound, into an *Error with the given message.
+func notFound(err error, format string, args ...any) error {
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrNotFound) {
+		return &Error{Kind: ErrNotFound, Message: fmt.Sprintf(format, args...), Err: err}
+	}
+
+	return err
+}
+
+// duplicate turns errors wrapping ErrDuplicateTitle, which backends return when a title is
+// already taken, into an *Error naming the title.
+func duplicate(err error, title string) error {
+	if errors.Is(err, ErrDuplicateTitle) {
+		return &Error{Kind: ErrDuplicateTitle, Message: fmt.Sprintf("a note titled %q already exists", title), Err: err}
+	}
+
+	return err
+}
@@ -1,27 +1,195 @@
 package notes
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrInvalidTag            = errors.New("tags must not be empty or contain whitespace or commas")
+	ErrUnknownConflictPolicy = errors.New("unknown conflict policy")
+)
+
 type Client struct {
 	nr NoteReader
 	nw NoteWriter
 }
 
+// TimestampFormat is the layout timestamps are stored in, always in UTC.
+const TimestampFormat = time.RFC3339
+
+// LegacyTimestampFormat is the layout timestamps were stored in, in local time, before they
+// were stored as RFC3339.
+const LegacyTimestampFormat = "2006-01-02 15:04:05"
+
+// FormatTimestamp formats t the way timestamps are stored.
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format(TimestampFormat)
+}
+
+// ParseTimestamp parses a stored timestamp, returning it in UTC. Timestamps in the legacy
+// format are assumed to be in local time.
+func ParseTimestamp(s string) (time.Time, error) {
+	t, err := time.Parse(TimestampFormat, s)
+	if err != nil {
+		var lerr error
+		if t, lerr = time.ParseInLocation(LegacyTimestampFormat, s, time.Local); lerr != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return t.UTC(), nil
+}
+
+// Now returns the current time, to the precision timestamps are stored at.
+func Now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
 type Note struct {
-	ID              int    `json:"id,omitempty"`
-	Title           string `json:"title,omitempty"`
-	Description     string `json:"description,omitempty"`
-	CreateTimestamp string `json:"createTimestamp,omitempty"`
+	ID          int    `json:"id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// CreateTimestamp is when the note was added.
+	CreateTimestamp time.Time `json:"createTimestamp"`
+	// UpdateTimestamp is when the note was last changed, or zero if it never has been.
+	UpdateTimestamp time.Time `json:"updateTimestamp"`
+	// LastUsedTimestamp is when the note was last copied, or zero if it never has been.
+	LastUsedTimestamp time.Time `json:"lastUsedTimestamp"`
+	// UseCount is the number of times the note has been copied.
+	UseCount int      `json:"useCount,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	// DeleteTimestamp is when the note was moved to the trash, or zero if it isn't in the trash.
+	DeleteTimestamp time.Time `json:"deleteTimestamp"`
+	// Placeholders are the names of the placeholders in the description. They aren't stored,
+	// but are filled in when a note is output.
+	Placeholders []string `json:"placeholders,omitempty"`
+}
+
+// note has the fields of Note without its methods, so that it can be embedded in noteJSON.
+type note Note
+
+// noteJSON is how a Note is encoded as JSON, omitting the timestamps that aren't set as
+// encoding/json can't omit an empty time.Time.
+type noteJSON struct {
+	note
+	CreateTimestamp   *time.Time `json:"createTimestamp,omitempty"`
+	UpdateTimestamp   *time.Time `json:"updateTimestamp,omitempty"`
+	LastUsedTimestamp *time.Time `json:"lastUsedTimestamp,omitempty"`
+	DeleteTimestamp   *time.Time `json:"deleteTimestamp,omitempty"`
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
+
+func (n Note) toJSON() noteJSON {
+	return noteJSON{
+		note:              note(n),
+		CreateTimestamp:   optionalTime(n.CreateTimestamp),
+		UpdateTimestamp:   optionalTime(n.UpdateTimestamp),
+		LastUsedTimestamp: optionalTime(n.LastUsedTimestamp),
+		DeleteTimestamp:   optionalTime(n.DeleteTimestamp),
+	}
+}
+
+func (n Note) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.toJSON())
+}
+
+// Revision is a previous version of a note, saved whenever the note is updated.
+type Revision struct {
+	NoteID          int       `json:"noteId"`
+	Revision        int       `json:"revision"`
+	CreateTimestamp time.Time `json:"createTimestamp"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+}
+
+// HighlightStart and HighlightEnd surround the matched fragments in a SearchResult's highlights.
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+type SearchResult struct {
+	Note
+	// Rank is the bm25 score of the match, lower is better.
+	Rank                 float64 `json:"rank"`
+	TitleHighlight       string  `json:"-"`
+	DescriptionHighlight string  `json:"-"`
+}
+
+func (r SearchResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		noteJSON
+		Rank float64 `json:"rank"`
+	}{r.Note.toJSON(), r.Rank})
+}
+
+// ConflictPolicy decides what happens when an imported note has the same title as an
+// existing one.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the existing note and ignores the imported one.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the existing note with the imported one.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictRename imports the note with a suffix added to its title.
+	ConflictRename ConflictPolicy = "rename"
+)
+
+// Validate returns ErrUnknownConflictPolicy if p isn't one of the known policies.
+func (p ConflictPolicy) Validate() error {
+	switch p {
+	case ConflictSkip, ConflictOverwrite, ConflictRename:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownConflictPolicy, p)
+	}
+}
+
+// ImportResult counts what happened to each of the notes passed to ImportNotes.
+type ImportResult struct {
+	Inserted    int `json:"inserted"`
+	Overwritten int `json:"overwritten"`
+	Renamed     int `json:"renamed"`
+	Skipped     int `json:"skipped"`
 }
 
 type NoteReader interface {
 	ListNotes() ([]Note, error)
+	SearchNotes(string) ([]SearchResult, error)
 	GetNoteByID(int) (*Note, error)
 	GetNoteByTitle(string) (*Note, error)
+	ListNoteRevisions(int) ([]Revision, error)
+	GetNoteRevision(int, int) (*Revision, error)
+	ListTrashedNotes() ([]Note, error)
 }
 
 type NoteWriter interface {
 	InsertNote(Note) (int, error)
 	UpdateNote(int, Note) (int64, error)
 	DeleteNote(int) error
+	TagNote(int, []string) error
+	UntagNote(int, []string) error
+	MarkNoteUsed(int) error
+	RestoreNoteRevision(int, int) (int64, error)
+	RestoreNote(int) error
+	PurgeNote(int) error
+	EmptyTrash(time.Time) (int64, error)
+	ImportNotes([]Note, ConflictPolicy) (ImportResult, error)
 }
 
 type NoteReaderWriter interface {
@@ -37,25 +205,315 @@ func New(nrw NoteReaderWriter) *Client {
 }
 
 func (c *Client) GetByID(id int) (*Note, error) {
-	return c.nr.GetNoteByID(id)
+	n, err := c.nr.GetNoteByID(id)
+	if err != nil {
+		return nil, notFound(err, "no note with id %d", id)
+	}
+
+	return n, nil
 }
 
 func (c *Client) GetByTitle(title string) (*Note, error) {
-	return c.nr.GetNoteByTitle(title)
+	n, err := c.nr.GetNoteByTitle(title)
+	if err != nil {
+		return nil, notFound(err, "no note with title %q", title)
+	}
+
+	return n, nil
 }
 
 func (c *Client) List() ([]Note, error) {
 	return c.nr.ListNotes()
 }
 
+func (c *Client) ListByTags(tags []string, matchAll bool) ([]Note, error) {
+	ns, err := c.nr.ListNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	return FilterByTags(ns, tags, matchAll), nil
+}
+
+func (c *Client) Search(query string) ([]SearchResult, error) {
+	return c.nr.SearchNotes(query)
+}
+
+// Create validates and adds a note, returning its ID. The create timestamp defaults to now.
 func (c *Client) Create(n Note) (int, error) {
-	return c.nw.InsertNote(n)
+	if err := validate(&n, false); err != nil {
+		return 0, err
+	}
+
+	if n.CreateTimestamp.IsZero() {
+		n.CreateTimestamp = Now()
+	}
+
+	id, err := c.nw.InsertNote(n)
+	if err != nil {
+		return 0, duplicate(err, n.Title)
+	}
+
+	return id, nil
 }
 
+// Update validates and applies the non-empty fields of n to a note. Tags are only changed if
+// they aren't nil.
 func (c *Client) Update(id int, n Note) (int64, error) {
-	return c.nw.UpdateNote(id, n)
+	if n.Title == "" && n.Description == "" && n.Tags == nil {
+		return 0, invalid(errNothingToUpdate, "%s", errNothingToUpdate)
+	}
+
+	if err := validate(&n, true); err != nil {
+		return 0, err
+	}
+
+	ra, err := c.nw.UpdateNote(id, n)
+	if err != nil {
+		return 0, notFound(duplicate(err, n.Title), "no note with id %d", id)
+	}
+
+	if ra == 0 {
+		return 0, notFound(ErrNotFound, "no note with id %d", id)
+	}
+
+	return ra, nil
 }
 
 func (c *Client) Delete(id int) error {
-	return c.nw.DeleteNote(id)
+	return notFound(c.nw.DeleteNote(id), "no note with id %d", id)
+}
+
+func (c *Client) Tag(id int, tags []string) error {
+	return c.changeTags(id, tags, c.nw.TagNote)
+}
+
+func (c *Client) Untag(id int, tags []string) error {
+	return c.changeTags(id, tags, c.nw.UntagNote)
+}
+
+// MarkUsed records that a note was used now, e.g. when it's copied, incrementing its use count.
+func (c *Client) MarkUsed(id int) error {
+	return notFound(c.nw.MarkNoteUsed(id), "no note with id %d", id)
+}
+
+func (c *Client) changeTags(id int, tags []string, change func(int, []string) error) error {
+	tags, err := NormaliseTags(tags)
+	if err != nil {
+		return invalid(err, "%s", err)
+	}
+
+	return notFound(change(id, tags), "no note with id %d", id)
+}
+
+// ParseNewlines replaces the literal newline sequences in a description with actual newlines,
+// trimming the whitespace around each line.
+func ParseNewlines(description string) string {
+	spl := strings.Split(description, "\\n")
+
+	out := make([]string, len(spl))
+	for i, s := range spl {
+		out[i] = strings.TrimSpace(s)
+	}
+
+	return strings.Join(out, "\n")
+}
+
+// MatchNotes searches notes without a full-text index, returning those whose title or
+// description contain every term in the query, ignoring case. Matches in the title rank higher
+// than matches in the description.
+func MatchNotes(ns []Note, query string) []SearchResult {
+	terms := strings.Fields(strings.ToLower(query))
+
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = regexp.QuoteMeta(t)
+	}
+
+	re := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+
+	out := make([]SearchResult, 0)
+
+	for _, n := range ns {
+		title, desc := strings.ToLower(n.Title), strings.ToLower(n.Description)
+
+		rank, matched := 0.0, len(terms) > 0
+		for _, t := range terms {
+			inTitle, inDesc := strings.Count(title, t), strings.Count(desc, t)
+			if inTitle+inDesc == 0 {
+				matched = false
+				break
+			}
+
+			rank -= float64(10*inTitle + inDesc)
+		}
+
+		if !matched {
+			continue
+		}
+
+		out = append(out, SearchResult{
+			Note:                 n,
+			Rank:                 rank,
+			TitleHighlight:       re.ReplaceAllString(n.Title, HighlightStart+"$0"+HighlightEnd),
+			DescriptionHighlight: re.ReplaceAllString(n.Description, HighlightStart+"$0"+HighlightEnd),
+		})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Rank < out[j].Rank })
+
+	return out
+}
+
+// FilterByTags returns the notes that have any of the given tags or, if matchAll is true,
+// all of them. If no tags are given then all notes are returned.
+func FilterByTags(ns []Note, tags []string, matchAll bool) []Note {
+	if len(tags) == 0 {
+		return ns
+	}
+
+	out := make([]Note, 0, len(ns))
+
+	for _, n := range ns {
+		has := make(map[string]bool, len(n.Tags))
+		for _, t := range n.Tags {
+			has[t] = true
+		}
+
+		matched := 0
+		for _, t := range tags {
+			if has[t] {
+				matched++
+			}
+		}
+
+		if (matchAll && matched == len(tags)) || (!matchAll && matched > 0) {
+			out = append(out, n)
+		}
+	}
+
+	return out
+}
+
+// FilterByCreated returns the notes created at or after since and before until. Either may be
+// zero to leave that end of the range open.
+func FilterByCreated(ns []Note, since, until time.Time) []Note {
+	if since.IsZero() && until.IsZero() {
+		return ns
+	}
+
+	out := make([]Note, 0, len(ns))
+
+	for _, n := range ns {
+		if !since.IsZero() && n.CreateTimestamp.Before(since) {
+			continue
+		}
+
+		if !until.IsZero() && !n.CreateTimestamp.Before(until) {
+			continue
+		}
+
+		out = append(out, n)
+	}
+
+	return out
+}
+
+// NormaliseTags trims, validates and de-duplicates tags. A nil slice is returned as nil, so
+// callers can tell "don't change the tags" apart from "remove all tags".
+func NormaliseTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(tags))
+	out := make([]string, 0, len(tags))
+
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+
+		if t == "" || strings.ContainsRune(t, ',') || strings.IndexFunc(t, unicode.IsSpace) != -1 {
+			return nil, ErrInvalidTag
+		}
+
+		if seen[t] {
+			continue
+		}
+
+		seen[t] = true
+		out = append(out, t)
+	}
+
+	return out, nil
+}
+
+// Revisions lists the previous versions of a note, oldest first.
+func (c *Client) Revisions(id int) ([]Revision, error) {
+	if _, err := c.GetByID(id); err != nil {
+		return nil, err
+	}
+
+	return c.nr.ListNoteRevisions(id)
+}
+
+func (c *Client) Revision(id int, revision int) (*Revision, error) {
+	r, err := c.nr.GetNoteRevision(id, revision)
+	if err != nil {
+		return nil, notFound(err, "note %d has no revision %d", id, revision)
+	}
+
+	return r, nil
+}
+
+// Restore updates a note to the title and description of one of its revisions.
+func (c *Client) Restore(id int, revision int) (int64, error) {
+	ra, err := c.nw.RestoreNoteRevision(id, revision)
+	if err != nil {
+		return 0, notFound(err, "note %d has no revision %d", id, revision)
+	}
+
+	if ra == 0 {
+		return 0, notFound(ErrNotFound, "no note with id %d", id)
+	}
+
+	return ra, nil
+}
+
+func (c *Client) Trash() ([]Note, error) {
+	return c.nr.ListTrashedNotes()
+}
+
+// Untrash takes a note out of the trash. It fails with ErrDuplicateTitle if another note has
+// been given its title since it was deleted.
+func (c *Client) Untrash(id int) error {
+	err := c.nw.RestoreNote(id)
+	if errors.Is(err, ErrDuplicateTitle) {
+		return &Error{Kind: ErrDuplicateTitle, Message: fmt.Sprintf("another note has the title of note %d, rename it before restoring this one", id), Err: err}
+	}
+
+	return notFound(err, "no note with id %d in the trash", id)
+}
+
+func (c *Client) Purge(id int) error {
+	return notFound(c.nw.PurgeNote(id), "no note with id %d", id)
+}
+
+func (c *Client) EmptyTrash(before time.Time) (int64, error) {
+	return c.nw.EmptyTrash(before)
+}
+
+// Import validates and adds the notes, handling title conflicts according to onConflict. If
+// any note is invalid then none are imported.
+func (c *Client) Import(ns []Note, onConflict ConflictPolicy) (ImportResult, error) {
+	valid := make([]Note, len(ns))
+
+	for i, n := range ns {
+		if err := validate(&n, false); err != nil {
+			return ImportResult{}, invalid(err, "note %d (%q): %s", i+1, n.Title, err)
+		}
+
+		valid[i] = n
+	}
+
+	return c.nw.ImportNotes(valid, onConflict)
 }
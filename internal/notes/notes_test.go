@@ -0,0 +1,67 @@
+package notes
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimestamps(t *testing.T) {
+	t.Run("should format in UTC", func(t *testing.T) {
+		ts := time.Date(2023, 9, 1, 11, 0, 0, 0, time.FixedZone("BST", 60*60))
+		assert.Equal(t, "2023-09-01T10:00:00Z", FormatTimestamp(ts))
+	})
+
+	t.Run("should parse RFC3339 and legacy timestamps", func(t *testing.T) {
+		ts, err := ParseTimestamp("2023-09-01T11:00:00+01:00")
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC), ts)
+
+		legacy, err := ParseTimestamp("2023-09-01 10:00:00")
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2023, 9, 1, 10, 0, 0, 0, time.Local).UTC(), legacy)
+
+		_, err = ParseTimestamp("yesterday")
+		assert.Error(t, err)
+	})
+}
+
+func TestNoteJSON(t *testing.T) {
+	created := time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	b, err := json.Marshal(Note{ID: 1, Title: "t", Description: "d", CreateTimestamp: created})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": 1, "title": "t", "description": "d", "createTimestamp": "2023-09-01T10:00:00Z"}`, string(b))
+
+	b, err = json.Marshal(SearchResult{Note: Note{ID: 1, LastUsedTimestamp: created}, Rank: -1.5})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id": 1, "lastUsedTimestamp": "2023-09-01T10:00:00Z", "rank": -1.5}`, string(b))
+
+	var n Note
+	require.NoError(t, json.Unmarshal([]byte(`{"id": 1, "createTimestamp": "2023-09-01T10:00:00Z"}`), &n))
+	assert.Equal(t, created, n.CreateTimestamp)
+	assert.True(t, n.UpdateTimestamp.IsZero())
+}
+
+func TestFilterByCreated(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 9, d, 0, 0, 0, 0, time.UTC) }
+
+	ns := []Note{{ID: 1, CreateTimestamp: day(1)}, {ID: 2, CreateTimestamp: day(2)}, {ID: 3, CreateTimestamp: day(3)}}
+
+	ids := func(ns []Note) []int {
+		out := make([]int, len(ns))
+		for i, n := range ns {
+			out[i] = n.ID
+		}
+
+		return out
+	}
+
+	assert.Equal(t, []int{1, 2, 3}, ids(FilterByCreated(ns, time.Time{}, time.Time{})))
+	assert.Equal(t, []int{2, 3}, ids(FilterByCreated(ns, day(2), time.Time{})))
+	assert.Equal(t, []int{1}, ids(FilterByCreated(ns, time.Time{}, day(2))))
+	assert.Equal(t, []int{2}, ids(FilterByCreated(ns, day(2), day(3))))
+}
@@ -0,0 +1,455 @@
+package notestest
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+// Conformance runs the tests that every notes.NoteReaderWriter must pass. newStore is called
+// for each group of tests and must return an empty store.
+func Conformance(t *testing.T, newStore func(t *testing.T) notes.NoteReaderWriter) {
+	t.Run("notes", func(t *testing.T) { testNotes(t, newStore(t)) })
+	t.Run("search", func(t *testing.T) { testSearch(t, newStore(t)) })
+	t.Run("tags", func(t *testing.T) { testTags(t, newStore(t)) })
+	t.Run("revisions", func(t *testing.T) { testRevisions(t, newStore(t)) })
+	t.Run("trash", func(t *testing.T) { testTrash(t, newStore(t)) })
+	t.Run("import", func(t *testing.T) { testImport(t, newStore(t)) })
+}
+
+// isNotFound reports whether err is one of the errors backends may use for a missing note.
+func isNotFound(err error) bool {
+	return errors.Is(err, sql.ErrNoRows) || errors.Is(err, notes.ErrNotFound)
+}
+
+func insert(t *testing.T, s notes.NoteReaderWriter, n notes.Note) int {
+	t.Helper()
+
+	if n.CreateTimestamp.IsZero() {
+		n.CreateTimestamp = notes.Now()
+	}
+
+	id, err := s.InsertNote(n)
+	require.NoError(t, err)
+	require.NotZero(t, id)
+
+	return id
+}
+
+func get(t *testing.T, s notes.NoteReaderWriter, id int) *notes.Note {
+	t.Helper()
+
+	n, err := s.GetNoteByID(id)
+	require.NoError(t, err)
+
+	return n
+}
+
+func testNotes(t *testing.T, s notes.NoteReaderWriter) {
+	// Timestamps should be stored in UTC, to the second
+	created := time.Date(2023, 9, 1, 11, 0, 0, 500, time.FixedZone("BST", 60*60))
+	note := notes.Note{Title: "title", Description: "description", CreateTimestamp: created, Tags: []string{"b", "a", "b"}}
+
+	id := insert(t, s, note)
+	otherID := insert(t, s, notes.Note{Title: "other", Description: "other"})
+
+	t.Run("should get the note by id and title", func(t *testing.T) {
+		want := &notes.Note{ID: id, Title: note.Title, Description: note.Description, CreateTimestamp: time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC), Tags: []string{"a", "b"}}
+
+		assert.Equal(t, want, get(t, s, id))
+
+		n, err := s.GetNoteByTitle(note.Title)
+		assert.NoError(t, err)
+		assert.Equal(t, want, n)
+	})
+
+	t.Run("should return not found for missing notes", func(t *testing.T) {
+		_, err := s.GetNoteByID(9009)
+		assert.True(t, isNotFound(err), "got %v", err)
+
+		_, err = s.GetNoteByTitle("missing")
+		assert.True(t, isNotFound(err), "got %v", err)
+	})
+
+	t.Run("should list the notes", func(t *testing.T) {
+		ns, err := s.ListNotes()
+		assert.NoError(t, err)
+
+		ids := make([]int, len(ns))
+		for i, n := range ns {
+			ids[i] = n.ID
+		}
+
+		assert.ElementsMatch(t, []int{id, otherID}, ids)
+	})
+
+	t.Run("should reject duplicate titles", func(t *testing.T) {
+		_, err := s.InsertNote(note)
+		assert.ErrorIs(t, err, notes.ErrDuplicateTitle)
+
+		_, err = s.UpdateNote(otherID, notes.Note{Title: note.Title})
+		assert.ErrorIs(t, err, notes.ErrDuplicateTitle)
+	})
+
+	t.Run("should update only the given fields", func(t *testing.T) {
+		before := notes.Now()
+
+		ra, err := s.UpdateNote(id, notes.Note{Description: "updated"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), ra)
+
+		n := get(t, s, id)
+		assert.Equal(t, note.Title, n.Title)
+		assert.Equal(t, "updated", n.Description)
+		assert.Equal(t, []string{"a", "b"}, n.Tags)
+		assert.False(t, n.UpdateTimestamp.Before(before), "updated at %s, before %s", n.UpdateTimestamp, before)
+		assert.Equal(t, time.UTC, n.UpdateTimestamp.Location())
+
+		ra, err = s.UpdateNote(id, notes.Note{Title: "renamed", Tags: []string{}})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), ra)
+
+		n = get(t, s, id)
+		assert.Equal(t, "renamed", n.Title)
+		assert.Equal(t, "updated", n.Description)
+		assert.Empty(t, n.Tags)
+	})
+
+	t.Run("should not update missing notes", func(t *testing.T) {
+		ra, err := s.UpdateNote(9009, notes.Note{Description: "updated"})
+		assert.NoError(t, err)
+		assert.Zero(t, ra)
+	})
+
+	t.Run("should mark notes as used", func(t *testing.T) {
+		assert.True(t, get(t, s, otherID).LastUsedTimestamp.IsZero())
+
+		before := notes.Now()
+		require.NoError(t, s.MarkNoteUsed(otherID))
+
+		n := get(t, s, otherID)
+		assert.False(t, n.LastUsedTimestamp.Before(before), "last used at %s, before %s", n.LastUsedTimestamp, before)
+		assert.True(t, n.UpdateTimestamp.IsZero(), "using a note doesn't change it")
+		assert.Equal(t, 1, n.UseCount)
+
+		require.NoError(t, s.MarkNoteUsed(otherID))
+		assert.Equal(t, 2, get(t, s, otherID).UseCount)
+
+		assert.True(t, isNotFound(s.MarkNoteUsed(9009)))
+	})
+}
+
+func testSearch(t *testing.T, s notes.NoteReaderWriter) {
+	inDesc := insert(t, s, notes.Note{Title: "pods", Description: "kubectl get pods"})
+	inTitle := insert(t, s, notes.Note{Title: "kubectl logs", Description: "show the logs of a pod"})
+	insert(t, s, notes.Note{Title: "psql", Description: "psql -h localhost"})
+
+	t.Run("should return an error for an empty query", func(t *testing.T) {
+		_, err := s.SearchNotes("  ")
+		assert.Error(t, err)
+	})
+
+	t.Run("should rank title matches first", func(t *testing.T) {
+		rs, err := s.SearchNotes("KUBECTL")
+		require.NoError(t, err)
+		require.Len(t, rs, 2)
+
+		assert.Equal(t, inTitle, rs[0].ID)
+		assert.Equal(t, inDesc, rs[1].ID)
+		assert.LessOrEqual(t, rs[0].Rank, rs[1].Rank)
+
+		assert.Contains(t, rs[0].TitleHighlight, notes.HighlightStart+"kubectl"+notes.HighlightEnd)
+		assert.Contains(t, rs[1].DescriptionHighlight, notes.HighlightStart+"kubectl"+notes.HighlightEnd)
+	})
+
+	t.Run("should require every term", func(t *testing.T) {
+		rs, err := s.SearchNotes("kubectl logs")
+		require.NoError(t, err)
+		require.Len(t, rs, 1)
+		assert.Equal(t, inTitle, rs[0].ID)
+	})
+
+	t.Run("should not return deleted notes", func(t *testing.T) {
+		require.NoError(t, s.DeleteNote(inTitle))
+
+		rs, err := s.SearchNotes("kubectl")
+		require.NoError(t, err)
+		require.Len(t, rs, 1)
+		assert.Equal(t, inDesc, rs[0].ID)
+	})
+}
+
+func testTags(t *testing.T, s notes.NoteReaderWriter) {
+	id := insert(t, s, notes.Note{Title: "title", Description: "description"})
+
+	t.Run("should reject invalid tags", func(t *testing.T) {
+		for _, tags := range [][]string{{""}, {"a b"}, {"a,b"}} {
+			assert.ErrorIs(t, s.TagNote(id, tags), notes.ErrInvalidTag)
+
+			_, err := s.InsertNote(notes.Note{Title: "invalid", Description: "d", Tags: tags})
+			assert.ErrorIs(t, err, notes.ErrInvalidTag)
+		}
+	})
+
+	t.Run("should add and remove tags", func(t *testing.T) {
+		require.NoError(t, s.TagNote(id, []string{"b", " a ", "c"}))
+		require.NoError(t, s.TagNote(id, []string{"a"}))
+		assert.Equal(t, []string{"a", "b", "c"}, get(t, s, id).Tags)
+		assert.False(t, get(t, s, id).UpdateTimestamp.IsZero())
+
+		require.NoError(t, s.UntagNote(id, []string{"b", "missing"}))
+		assert.Equal(t, []string{"a", "c"}, get(t, s, id).Tags)
+
+		require.NoError(t, s.UntagNote(id, []string{"a", "c"}))
+		assert.Empty(t, get(t, s, id).Tags)
+	})
+
+	t.Run("should return not found for missing notes", func(t *testing.T) {
+		assert.True(t, isNotFound(s.TagNote(9009, []string{"a"})))
+		assert.True(t, isNotFound(s.UntagNote(9009, []string{"a"})))
+	})
+}
+
+func testRevisions(t *testing.T, s notes.NoteReaderWriter) {
+	id := insert(t, s, notes.Note{Title: "v1", Description: "one"})
+
+	t.Run("should start without revisions", func(t *testing.T) {
+		rs, err := s.ListNoteRevisions(id)
+		assert.NoError(t, err)
+		assert.Empty(t, rs)
+
+		_, err = s.GetNoteRevision(id, 1)
+		assert.True(t, isNotFound(err), "got %v", err)
+	})
+
+	t.Run("should save the previous version on update", func(t *testing.T) {
+		_, err := s.UpdateNote(id, notes.Note{Title: "v2", Description: "two"})
+		require.NoError(t, err)
+
+		_, err = s.UpdateNote(id, notes.Note{Tags: []string{"tags-only"}})
+		require.NoError(t, err)
+
+		rs, err := s.ListNoteRevisions(id)
+		assert.NoError(t, err)
+		require.Len(t, rs, 1)
+		assert.Equal(t, id, rs[0].NoteID)
+		assert.Equal(t, 1, rs[0].Revision)
+		assert.Equal(t, "v1", rs[0].Title)
+		assert.Equal(t, "one", rs[0].Description)
+		assert.NotEmpty(t, rs[0].CreateTimestamp)
+
+		r, err := s.GetNoteRevision(id, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, rs[0], *r)
+	})
+
+	t.Run("should restore a revision", func(t *testing.T) {
+		ra, err := s.RestoreNoteRevision(id, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), ra)
+
+		n := get(t, s, id)
+		assert.Equal(t, "v1", n.Title)
+		assert.Equal(t, "one", n.Description)
+
+		rs, err := s.ListNoteRevisions(id)
+		assert.NoError(t, err)
+		require.Len(t, rs, 2)
+		assert.Equal(t, "v2", rs[1].Title)
+
+		_, err = s.RestoreNoteRevision(id, 9009)
+		assert.True(t, isNotFound(err), "got %v", err)
+	})
+
+	t.Run("should not save a revision when updating a deleted note", func(t *testing.T) {
+		trashed := insert(t, s, notes.Note{Title: "trashed", Description: "one"})
+		require.NoError(t, s.DeleteNote(trashed))
+
+		ra, err := s.UpdateNote(trashed, notes.Note{Description: "two"})
+		assert.NoError(t, err)
+		assert.Zero(t, ra)
+
+		rs, err := s.ListNoteRevisions(trashed)
+		assert.NoError(t, err)
+		assert.Empty(t, rs)
+	})
+}
+
+func testTrash(t *testing.T, s notes.NoteReaderWriter) {
+	note := notes.Note{Title: "title", Description: "description"}
+	id := insert(t, s, note)
+
+	t.Run("should hide deleted notes", func(t *testing.T) {
+		require.NoError(t, s.DeleteNote(id))
+		assert.True(t, isNotFound(s.DeleteNote(id)))
+
+		_, err := s.GetNoteByID(id)
+		assert.True(t, isNotFound(err), "got %v", err)
+
+		_, err = s.GetNoteByTitle(note.Title)
+		assert.True(t, isNotFound(err), "got %v", err)
+
+		ra, err := s.UpdateNote(id, notes.Note{Description: "updated"})
+		assert.NoError(t, err)
+		assert.Zero(t, ra)
+
+		ns, err := s.ListNotes()
+		assert.NoError(t, err)
+		assert.Empty(t, ns)
+
+		ns, err = s.ListTrashedNotes()
+		assert.NoError(t, err)
+		require.Len(t, ns, 1)
+		assert.Equal(t, id, ns[0].ID)
+		assert.NotEmpty(t, ns[0].DeleteTimestamp)
+	})
+
+	t.Run("should allow the title of deleted notes to be reused", func(t *testing.T) {
+		reused := insert(t, s, note)
+		assert.Equal(t, note.Title, get(t, s, reused).Title)
+
+		assert.ErrorIs(t, s.RestoreNote(id), notes.ErrDuplicateTitle)
+
+		ns, err := s.ListTrashedNotes()
+		assert.NoError(t, err)
+		require.Len(t, ns, 1)
+		assert.Equal(t, id, ns[0].ID)
+
+		require.NoError(t, s.PurgeNote(reused))
+	})
+
+	t.Run("should restore deleted notes", func(t *testing.T) {
+		require.NoError(t, s.RestoreNote(id))
+		assert.True(t, isNotFound(s.RestoreNote(id)))
+		assert.True(t, isNotFound(s.RestoreNote(9009)))
+
+		assert.Empty(t, get(t, s, id).DeleteTimestamp)
+	})
+
+	t.Run("should empty notes deleted before the given time", func(t *testing.T) {
+		require.NoError(t, s.DeleteNote(id))
+
+		purged, err := s.EmptyTrash(time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Zero(t, purged)
+
+		purged, err = s.EmptyTrash(time.Time{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+
+		ns, err := s.ListTrashedNotes()
+		assert.NoError(t, err)
+		assert.Empty(t, ns)
+	})
+
+	t.Run("should purge notes whether or not they are deleted", func(t *testing.T) {
+		id := insert(t, s, note)
+
+		require.NoError(t, s.PurgeNote(id))
+		assert.True(t, isNotFound(s.PurgeNote(id)))
+
+		_, err := s.GetNoteByID(id)
+		assert.True(t, isNotFound(err), "got %v", err)
+
+		// The title is free again
+		insert(t, s, note)
+	})
+}
+
+func testImport(t *testing.T, s notes.NoteReaderWriter) {
+	id := insert(t, s, notes.Note{Title: "existing", Description: "old", CreateTimestamp: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), Tags: []string{"old"}})
+
+	imported := []notes.Note{
+		{Title: "existing", Description: "new", CreateTimestamp: time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC), LastUsedTimestamp: time.Date(2022, 6, 5, 9, 0, 0, 0, time.UTC), UseCount: 3, Tags: []string{"new"}},
+		{
+			Title:             "other",
+			Description:       "other",
+			CreateTimestamp:   time.Date(2022, 6, 2, 12, 0, 0, 0, time.UTC),
+			UpdateTimestamp:   time.Date(2022, 6, 3, 12, 0, 0, 0, time.UTC),
+			LastUsedTimestamp: time.Date(2022, 6, 4, 12, 0, 0, 0, time.UTC),
+			UseCount:          5,
+		},
+	}
+
+	t.Run("should reject unknown conflict policies", func(t *testing.T) {
+		_, err := s.ImportNotes(imported, "merge")
+		assert.ErrorIs(t, err, notes.ErrUnknownConflictPolicy)
+	})
+
+	t.Run("should import nothing if any note is invalid", func(t *testing.T) {
+		_, err := s.ImportNotes(append(imported, notes.Note{Title: "bad", Description: "bad", Tags: []string{"a b"}}), notes.ConflictSkip)
+		assert.ErrorIs(t, err, notes.ErrInvalidTag)
+
+		ns, err := s.ListNotes()
+		assert.NoError(t, err)
+		assert.Len(t, ns, 1)
+	})
+
+	t.Run("should skip conflicting notes", func(t *testing.T) {
+		res, err := s.ImportNotes(imported, notes.ConflictSkip)
+		require.NoError(t, err)
+		assert.Equal(t, notes.ImportResult{Inserted: 1, Skipped: 1}, res)
+
+		n, err := s.GetNoteByTitle("other")
+		require.NoError(t, err)
+		assert.Equal(t, imported[1].CreateTimestamp, n.CreateTimestamp)
+		assert.Equal(t, imported[1].UpdateTimestamp, n.UpdateTimestamp)
+		assert.Equal(t, imported[1].LastUsedTimestamp, n.LastUsedTimestamp)
+		assert.Equal(t, imported[1].UseCount, n.UseCount)
+
+		assert.Equal(t, "old", get(t, s, id).Description)
+	})
+
+	t.Run("should rename conflicting notes", func(t *testing.T) {
+		res, err := s.ImportNotes(imported[:1], notes.ConflictRename)
+		require.NoError(t, err)
+		assert.Equal(t, notes.ImportResult{Renamed: 1}, res)
+
+		n, err := s.GetNoteByTitle("existing (2)")
+		require.NoError(t, err)
+		assert.Equal(t, "new", n.Description)
+		assert.Equal(t, []string{"new"}, n.Tags)
+	})
+
+	t.Run("should overwrite conflicting notes", func(t *testing.T) {
+		res, err := s.ImportNotes(imported[:1], notes.ConflictOverwrite)
+		require.NoError(t, err)
+		assert.Equal(t, notes.ImportResult{Overwritten: 1}, res)
+
+		n := get(t, s, id)
+		assert.Equal(t, "new", n.Description)
+		assert.Equal(t, imported[0].CreateTimestamp, n.CreateTimestamp)
+		assert.False(t, n.UpdateTimestamp.IsZero())
+		assert.Equal(t, imported[0].LastUsedTimestamp, n.LastUsedTimestamp)
+		assert.Equal(t, imported[0].UseCount, n.UseCount)
+		assert.Equal(t, []string{"new"}, n.Tags)
+
+		rs, err := s.ListNoteRevisions(id)
+		assert.NoError(t, err)
+		require.Len(t, rs, 1)
+		assert.Equal(t, "old", rs[0].Description)
+	})
+
+	t.Run("should not conflict with deleted notes", func(t *testing.T) {
+		require.NoError(t, s.DeleteNote(id))
+
+		res, err := s.ImportNotes(imported[:1], notes.ConflictSkip)
+		require.NoError(t, err)
+		assert.Equal(t, notes.ImportResult{Inserted: 1}, res)
+
+		n, err := s.GetNoteByTitle("existing")
+		require.NoError(t, err)
+		assert.NotEqual(t, id, n.ID)
+
+		ns, err := s.ListTrashedNotes()
+		assert.NoError(t, err)
+		require.Len(t, ns, 1)
+		assert.Equal(t, id, ns[0].ID)
+	})
+}
@@ -0,0 +1,509 @@
+// Package notestest provides an in-memory notes.NoteReaderWriter and a conformance suite
+// that every implementation of notes.NoteReaderWriter should pass.
+package notestest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+var (
+	ErrDeleteFailed  = fmt.Errorf("delete failed: %w", notes.ErrNotFound)
+	ErrRestoreFailed = fmt.Errorf("restore failed: %w", notes.ErrNotFound)
+)
+
+// Store is an in-memory notes.NoteReaderWriter for tests. It's safe for concurrent use.
+type Store struct {
+	mu     sync.Mutex
+	notes  []*record
+	nextID int
+}
+
+type record struct {
+	note      notes.Note
+	revisions []notes.Revision
+}
+
+var _ notes.NoteReaderWriter = (*Store)(nil)
+
+// New returns an empty store. Any notes given are inserted, and it panics if they're invalid.
+func New(ns ...notes.Note) *Store {
+	s := &Store{nextID: 1}
+
+	for _, n := range ns {
+		if _, err := s.InsertNote(n); err != nil {
+			panic(err)
+		}
+	}
+
+	return s
+}
+
+func now() time.Time {
+	return notes.Now()
+}
+
+func copyNote(n notes.Note) notes.Note {
+	if len(n.Tags) == 0 {
+		n.Tags = nil
+	} else {
+		n.Tags = append([]string(nil), n.Tags...)
+		sort.Strings(n.Tags)
+	}
+
+	return n
+}
+
+func (s *Store) find(id int) *record {
+	for _, r := range s.notes {
+		if r.note.ID == id {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) live(id int) *record {
+	if r := s.find(id); r != nil && r.note.DeleteTimestamp.IsZero() {
+		return r
+	}
+
+	return nil
+}
+
+// byTitle returns the note with the given title that isn't in the trash, as titles only need
+// to be unique among those notes.
+func (s *Store) byTitle(title string) *record {
+	for _, r := range s.notes {
+		if r.note.Title == title && r.note.DeleteTimestamp.IsZero() {
+			return r
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) insert(n notes.Note, tags []string) int {
+	n.ID = s.nextID
+	n.Tags = tags
+	n.CreateTimestamp = n.CreateTimestamp.UTC().Truncate(time.Second)
+	n.UpdateTimestamp, n.LastUsedTimestamp, n.DeleteTimestamp = time.Time{}, time.Time{}, time.Time{}
+	n.UseCount = 0
+	s.nextID++
+
+	s.notes = append(s.notes, &record{note: n})
+
+	return n.ID
+}
+
+func (r *record) saveRevision() {
+	r.revisions = append(r.revisions, notes.Revision{
+		NoteID:          r.note.ID,
+		Revision:        len(r.revisions) + 1,
+		CreateTimestamp: now(),
+		Title:           r.note.Title,
+		Description:     r.note.Description,
+	})
+}
+
+func (s *Store) list(trashed bool) []notes.Note {
+	out := make([]notes.Note, 0, len(s.notes))
+	for _, r := range s.notes {
+		if !r.note.DeleteTimestamp.IsZero() == trashed {
+			out = append(out, copyNote(r.note))
+		}
+	}
+
+	return out
+}
+
+func (s *Store) ListNotes() ([]notes.Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list(false), nil
+}
+
+// SearchNotes returns the notes whose title or description contain every term in the query,
+// ignoring case, with title matches ranked first.
+func (s *Store) SearchNotes(query string) ([]notes.SearchResult, error) {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("search query must not be empty")
+	}
+
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = regexp.QuoteMeta(t)
+	}
+
+	re := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	highlight := notes.HighlightStart + "$0" + notes.HighlightEnd
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]notes.SearchResult, 0)
+
+	for _, n := range s.list(false) {
+		title, desc := strings.ToLower(n.Title), strings.ToLower(n.Description)
+
+		rank, matched := 0.0, true
+		for _, t := range terms {
+			inTitle, inDesc := strings.Count(title, t), strings.Count(desc, t)
+			if inTitle+inDesc == 0 {
+				matched = false
+				break
+			}
+
+			rank -= float64(10*inTitle + inDesc)
+		}
+
+		if matched {
+			out = append(out, notes.SearchResult{
+				Note:                 n,
+				Rank:                 rank,
+				TitleHighlight:       re.ReplaceAllString(n.Title, highlight),
+				DescriptionHighlight: re.ReplaceAllString(n.Description, highlight),
+			})
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Rank < out[j].Rank })
+
+	return out, nil
+}
+
+func (s *Store) GetNoteByID(id int) (*notes.Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.live(id)
+	if r == nil {
+		return nil, notes.ErrNotFound
+	}
+
+	n := copyNote(r.note)
+
+	return &n, nil
+}
+
+func (s *Store) GetNoteByTitle(title string) (*notes.Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.byTitle(title)
+	if r == nil || !r.note.DeleteTimestamp.IsZero() {
+		return nil, notes.ErrNotFound
+	}
+
+	n := copyNote(r.note)
+
+	return &n, nil
+}
+
+func (s *Store) InsertNote(n notes.Note) (int, error) {
+	tags, err := notes.NormaliseTags(n.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.byTitle(n.Title) != nil {
+		return 0, fmt.Errorf("%w: %q", notes.ErrDuplicateTitle, n.Title)
+	}
+
+	return s.insert(n, tags), nil
+}
+
+func (s *Store) UpdateNote(id int, n notes.Note) (int64, error) {
+	if n.Title == "" && n.Description == "" && n.Tags == nil {
+		return 0, fmt.Errorf("at least one field to update must be provided")
+	}
+
+	tags, err := notes.NormaliseTags(n.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.live(id)
+	if r == nil {
+		return 0, nil
+	}
+
+	if other := s.byTitle(n.Title); n.Title != "" && other != nil && other != r {
+		return 0, fmt.Errorf("%w: %q", notes.ErrDuplicateTitle, n.Title)
+	}
+
+	if n.Title != "" || n.Description != "" {
+		r.saveRevision()
+	}
+
+	if n.Title != "" {
+		r.note.Title = n.Title
+	}
+
+	if n.Description != "" {
+		r.note.Description = n.Description
+	}
+
+	if tags != nil {
+		r.note.Tags = tags
+	}
+
+	r.note.UpdateTimestamp = now()
+
+	return 1, nil
+}
+
+// DeleteNote moves a note to the trash.
+func (s *Store) DeleteNote(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.live(id)
+	if r == nil {
+		return ErrDeleteFailed
+	}
+
+	r.note.DeleteTimestamp = now()
+
+	return nil
+}
+
+func (s *Store) TagNote(id int, tags []string) error {
+	return s.changeTags(id, tags, func(r *record, tags []string) {
+		r.note.Tags, _ = notes.NormaliseTags(append(r.note.Tags, tags...))
+	})
+}
+
+func (s *Store) UntagNote(id int, tags []string) error {
+	return s.changeTags(id, tags, func(r *record, tags []string) {
+		kept := make([]string, 0, len(r.note.Tags))
+		for _, t := range r.note.Tags {
+			if !contains(tags, t) {
+				kept = append(kept, t)
+			}
+		}
+
+		r.note.Tags = kept
+	})
+}
+
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Store) changeTags(id int, tags []string, change func(*record, []string)) error {
+	tags, err := notes.NormaliseTags(tags)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.live(id)
+	if r == nil {
+		return notes.ErrNotFound
+	}
+
+	change(r, tags)
+	r.note.UpdateTimestamp = now()
+
+	return nil
+}
+
+func (s *Store) MarkNoteUsed(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.live(id)
+	if r == nil {
+		return notes.ErrNotFound
+	}
+
+	r.note.LastUsedTimestamp = now()
+	r.note.UseCount++
+
+	return nil
+}
+
+func (s *Store) ListNoteRevisions(id int) ([]notes.Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]notes.Revision, 0)
+	if r := s.find(id); r != nil {
+		out = append(out, r.revisions...)
+	}
+
+	return out, nil
+}
+
+func (s *Store) GetNoteRevision(id int, revision int) (*notes.Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r := s.find(id); r != nil && revision > 0 && revision <= len(r.revisions) {
+		rev := r.revisions[revision-1]
+		return &rev, nil
+	}
+
+	return nil, notes.ErrNotFound
+}
+
+func (s *Store) RestoreNoteRevision(id int, revision int) (int64, error) {
+	rev, err := s.GetNoteRevision(id, revision)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.UpdateNote(id, notes.Note{Title: rev.Title, Description: rev.Description})
+}
+
+func (s *Store) ListTrashedNotes() ([]notes.Note, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.list(true), nil
+}
+
+func (s *Store) RestoreNote(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.find(id)
+	if r == nil || r.note.DeleteTimestamp.IsZero() {
+		return ErrRestoreFailed
+	}
+
+	if s.byTitle(r.note.Title) != nil {
+		return fmt.Errorf("%w: %q", notes.ErrDuplicateTitle, r.note.Title)
+	}
+
+	r.note.DeleteTimestamp = time.Time{}
+
+	return nil
+}
+
+func (s *Store) PurgeNote(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.purge(func(n notes.Note) bool { return n.ID == id }) == 0 {
+		return ErrDeleteFailed
+	}
+
+	return nil
+}
+
+func (s *Store) EmptyTrash(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.purge(func(n notes.Note) bool {
+		return !n.DeleteTimestamp.IsZero() && (before.IsZero() || n.DeleteTimestamp.Before(before))
+	}), nil
+}
+
+func (s *Store) purge(match func(notes.Note) bool) int64 {
+	var purged int64
+
+	kept := s.notes[:0]
+	for _, r := range s.notes {
+		if match(r.note) {
+			purged++
+			continue
+		}
+
+		kept = append(kept, r)
+	}
+
+	s.notes = kept
+
+	return purged
+}
+
+// ImportNotes adds the notes, either all of them or, if any is invalid, none.
+func (s *Store) ImportNotes(ns []notes.Note, onConflict notes.ConflictPolicy) (notes.ImportResult, error) {
+	var res notes.ImportResult
+
+	if err := onConflict.Validate(); err != nil {
+		return res, err
+	}
+
+	all := make([][]string, len(ns))
+	for i, n := range ns {
+		tags, err := notes.NormaliseTags(n.Tags)
+		if err != nil {
+			return res, fmt.Errorf("note %q: %w", n.Title, err)
+		}
+
+		all[i] = tags
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created := now()
+
+	for i, n := range ns {
+		if n.CreateTimestamp.IsZero() {
+			n.CreateTimestamp = created
+		}
+
+		existing := s.byTitle(n.Title)
+
+		switch {
+		case existing == nil:
+			res.Inserted++
+		case onConflict == notes.ConflictSkip:
+			res.Skipped++
+			continue
+		case onConflict == notes.ConflictOverwrite:
+			existing.saveRevision()
+			existing.note.Description = n.Description
+			existing.note.CreateTimestamp = n.CreateTimestamp.UTC().Truncate(time.Second)
+			existing.note.UpdateTimestamp = created
+			existing.note.LastUsedTimestamp = n.LastUsedTimestamp.UTC().Truncate(time.Second)
+			existing.note.UseCount = n.UseCount
+			existing.note.Tags = all[i]
+
+			res.Overwritten++
+			continue
+		case onConflict == notes.ConflictRename:
+			title := n.Title
+			for j := 2; s.byTitle(n.Title) != nil; j++ {
+				n.Title = fmt.Sprintf("%s (%d)", title, j)
+			}
+
+			res.Renamed++
+		}
+
+		r := s.find(s.insert(n, all[i]))
+		r.note.UpdateTimestamp = n.UpdateTimestamp.UTC().Truncate(time.Second)
+		r.note.LastUsedTimestamp = n.LastUsedTimestamp.UTC().Truncate(time.Second)
+		r.note.UseCount = n.UseCount
+	}
+
+	return res, nil
+}
@@ -0,0 +1,13 @@
+package notestest
+
+import (
+	"testing"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+func TestStore(t *testing.T) {
+	Conformance(t, func(t *testing.T) notes.NoteReaderWriter {
+		return New()
+	})
+}
@@ -0,0 +1,193 @@
+package notes
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrUnknownSortOrder = errors.New("unknown sort order")
+
+// SortOrder is the order notes are listed in.
+type SortOrder string
+
+const (
+	// SortID lists the notes in the order they were added, which is the default.
+	SortID SortOrder = "id"
+	// SortFrecency lists the notes used most often and most recently first.
+	SortFrecency SortOrder = "frecency"
+	// SortRecent lists the most recently used notes first, followed by the unused notes.
+	SortRecent SortOrder = "recent"
+	// SortCreated lists the newest notes first.
+	SortCreated SortOrder = "created"
+	// SortTitle lists the notes alphabetically by title.
+	SortTitle SortOrder = "title"
+	// SortUses lists the most used notes first.
+	SortUses SortOrder = "uses"
+)
+
+// Validate returns ErrUnknownSortOrder if o isn't one of the known sort orders.
+func (o SortOrder) Validate() error {
+	switch o {
+	case SortID, SortFrecency, SortRecent, SortCreated, SortTitle, SortUses:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownSortOrder, o)
+	}
+}
+
+// Frecency scores a note by how often it's been used, weighted by how recently it was last
+// used, so that a note copied a few times today ranks above one copied often months ago.
+func Frecency(n Note, now time.Time) float64 {
+	if n.UseCount == 0 || n.LastUsedTimestamp.IsZero() {
+		return 0
+	}
+
+	var weight float64
+
+	switch age := now.Sub(n.LastUsedTimestamp); {
+	case age < 4*time.Hour:
+		weight = 100
+	case age < 24*time.Hour:
+		weight = 70
+	case age < 7*24*time.Hour:
+		weight = 50
+	case age < 30*24*time.Hour:
+		weight = 30
+	case age < 90*24*time.Hour:
+		weight = 10
+	default:
+		weight = 1
+	}
+
+	return float64(n.UseCount) * weight
+}
+
+// Sort sorts the notes in place by the given order, with ties broken by ID. Frecency is
+// scored relative to now.
+func Sort(ns []Note, by SortOrder, now time.Time) error {
+	if err := by.Validate(); err != nil {
+		return err
+	}
+
+	var less func(a, b *Note) bool
+
+	switch by {
+	case SortID:
+		less = func(a, b *Note) bool { return false }
+	case SortFrecency:
+		scores := make(map[int]float64, len(ns))
+		for _, n := range ns {
+			scores[n.ID] = Frecency(n, now)
+		}
+
+		less = func(a, b *Note) bool { return scores[a.ID] > scores[b.ID] }
+	case SortRecent:
+		less = func(a, b *Note) bool { return a.LastUsedTimestamp.After(b.LastUsedTimestamp) }
+	case SortCreated:
+		less = func(a, b *Note) bool { return a.CreateTimestamp.After(b.CreateTimestamp) }
+	case SortTitle:
+		less = func(a, b *Note) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) }
+	case SortUses:
+		less = func(a, b *Note) bool { return a.UseCount > b.UseCount }
+	}
+
+	sort.SliceStable(ns, func(i, j int) bool {
+		a, b := &ns[i], &ns[j]
+		if less(a, b) {
+			return true
+		}
+
+		if less(b, a) {
+			return false
+		}
+
+		return a.ID < b.ID
+	})
+
+	return nil
+}
+
+// LastUsed returns the most recently used note.
+func (c *Client) LastUsed() (*Note, error) {
+	ns, err := c.nr.ListNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	var last *Note
+
+	for i, n := range ns {
+		if !n.LastUsedTimestamp.IsZero() && (last == nil || n.LastUsedTimestamp.After(last.LastUsedTimestamp)) {
+			last = &ns[i]
+		}
+	}
+
+	if last == nil {
+		return nil, notFound(ErrNotFound, "no note has been used yet")
+	}
+
+	return last, nil
+}
+
+// Stats summarises the notes and how they've been used.
+type Stats struct {
+	Notes   int `json:"notes"`
+	Trashed int `json:"trashed"`
+	Tags    int `json:"tags"`
+	// Used is the number of notes that have been used at least once, and Uses the total
+	// number of times notes have been used.
+	Used int `json:"used"`
+	Uses int `json:"uses"`
+	// Top are the most used notes, most used first.
+	Top []Note `json:"top"`
+}
+
+// Stats counts the notes, tags and uses, including up to top of the most used notes.
+func (c *Client) Stats(top int) (*Stats, error) {
+	ns, err := c.nr.ListNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	trashed, err := c.nr.ListTrashedNotes()
+	if err != nil {
+		return nil, err
+	}
+
+	if top < 0 {
+		top = 0
+	}
+
+	s := &Stats{Notes: len(ns), Trashed: len(trashed), Top: make([]Note, 0, top)}
+
+	tags := map[string]struct{}{}
+
+	for _, n := range ns {
+		for _, t := range n.Tags {
+			tags[t] = struct{}{}
+		}
+
+		if n.UseCount > 0 {
+			s.Used++
+			s.Uses += n.UseCount
+		}
+	}
+
+	s.Tags = len(tags)
+
+	// Sorting by uses can't fail
+	_ = Sort(ns, SortUses, time.Time{})
+
+	for _, n := range ns {
+		if len(s.Top) == top || n.UseCount == 0 {
+			break
+		}
+
+		s.Top = append(s.Top, n)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,58 @@
+package notes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrecency(t *testing.T) {
+	now := time.Date(2023, 9, 10, 12, 0, 0, 0, time.UTC)
+
+	assert.Zero(t, Frecency(Note{}, now))
+	assert.Greater(t, Frecency(Note{UseCount: 3, LastUsedTimestamp: now.Add(-time.Hour)}, now), Frecency(Note{UseCount: 20, LastUsedTimestamp: now.AddDate(0, -6, 0)}, now))
+	assert.Greater(t, Frecency(Note{UseCount: 2, LastUsedTimestamp: now}, now), Frecency(Note{UseCount: 1, LastUsedTimestamp: now}, now))
+}
+
+func TestSort(t *testing.T) {
+	now := time.Date(2023, 9, 10, 12, 0, 0, 0, time.UTC)
+
+	ns := []Note{
+		{ID: 1, Title: "b", CreateTimestamp: now.AddDate(0, 0, -3), UseCount: 20, LastUsedTimestamp: now.AddDate(0, -6, 0)},
+		{ID: 2, Title: "C", CreateTimestamp: now.AddDate(0, 0, -2), UseCount: 3, LastUsedTimestamp: now.Add(-time.Hour)},
+		{ID: 3, Title: "a", CreateTimestamp: now.AddDate(0, 0, -1)},
+		{ID: 4, Title: "d", CreateTimestamp: now.AddDate(0, 0, -1)},
+	}
+
+	tests := []struct {
+		by   SortOrder
+		want []int
+	}{
+		{by: SortID, want: []int{1, 2, 3, 4}},
+		{by: SortFrecency, want: []int{2, 1, 3, 4}},
+		{by: SortRecent, want: []int{2, 1, 3, 4}},
+		{by: SortCreated, want: []int{3, 4, 2, 1}},
+		{by: SortTitle, want: []int{3, 1, 2, 4}},
+		{by: SortUses, want: []int{1, 2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.by), func(t *testing.T) {
+			sorted := append([]Note(nil), ns...)
+			require.NoError(t, Sort(sorted, tt.by, now))
+
+			ids := make([]int, len(sorted))
+			for i, n := range sorted {
+				ids[i] = n.ID
+			}
+
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		assert.ErrorIs(t, Sort(ns, "random", now), ErrUnknownSortOrder)
+	})
+}
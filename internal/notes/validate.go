@@ -0,0 +1,53 @@
+package notes
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxTitleLength is the maximum number of characters in a title.
+const MaxTitleLength = 200
+
+// validate trims the title and description of the note and checks they, and the tags, are
+// valid. If partial is true, as for updates, empty fields are left out of the checks as
+// they aren't being changed.
+func validate(n *Note, partial bool) error {
+	title := strings.TrimSpace(n.Title)
+
+	switch {
+	case title == "" && (n.Title != "" || !partial):
+		return invalid(nil, "the title must not be empty")
+	case utf8.RuneCountInString(title) > MaxTitleLength:
+		return invalid(nil, "the title must not be longer than %d characters", MaxTitleLength)
+	case strings.IndexFunc(title, unicode.IsControl) != -1:
+		return invalid(nil, "the title must not contain control characters")
+	}
+
+	// Leading indentation is kept, as it may matter in a snippet
+	description := strings.TrimLeft(strings.TrimRightFunc(n.Description, unicode.IsSpace), "\r\n")
+
+	switch {
+	case strings.TrimSpace(description) == "" && (n.Description != "" || !partial):
+		return invalid(nil, "the description must not be empty")
+	case strings.IndexFunc(description, isDisallowedControl) != -1:
+		return invalid(nil, "the description must not contain control characters other than newlines and tabs")
+	}
+
+	tags, err := NormaliseTags(n.Tags)
+	if err != nil {
+		return invalid(err, "%s", err)
+	}
+
+	n.Title, n.Description, n.Tags = title, description, tags
+
+	return nil
+}
+
+func isDisallowedControl(r rune) bool {
+	return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t'
+}
+
+// errNothingToUpdate is returned when an update doesn't change anything.
+var errNothingToUpdate = errors.New("at least one of the title, description or tags must be given")
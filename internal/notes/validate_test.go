@@ -0,0 +1,64 @@
+package notes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("should trim the title and description", func(t *testing.T) {
+		n := Note{Title: "  title \n", Description: "\n  indented\n\tline\n\n", Tags: []string{" a ", "a"}}
+
+		require.NoError(t, validate(&n, false))
+		assert.Equal(t, Note{Title: "title", Description: "  indented\n\tline", Tags: []string{"a"}}, n)
+	})
+
+	t.Run("should allow empty fields in partial updates", func(t *testing.T) {
+		n := Note{Description: "d"}
+
+		require.NoError(t, validate(&n, true))
+		assert.Equal(t, Note{Description: "d"}, n)
+	})
+
+	tests := []struct {
+		name    string
+		note    Note
+		partial bool
+	}{
+		{name: "an empty title", note: Note{Description: "d"}},
+		{name: "a blank title in an update", note: Note{Title: " "}, partial: true},
+		{name: "a long title", note: Note{Title: strings.Repeat("x", MaxTitleLength+1), Description: "d"}},
+		{name: "control characters in the title", note: Note{Title: "a\nb", Description: "d"}},
+		{name: "an empty description", note: Note{Title: "t", Description: " \n "}},
+		{name: "control characters in the description", note: Note{Title: "t", Description: "\x1b[31mred"}},
+		{name: "invalid tags", note: Note{Title: "t", Description: "d", Tags: []string{"a b"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run("should reject "+tt.name, func(t *testing.T) {
+			err := validate(&tt.note, tt.partial)
+			assert.ErrorIs(t, err, ErrInvalidNote)
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	err := notFound(sql.ErrNoRows, "no note with title %q", "x")
+	assert.EqualError(t, err, `no note with title "x"`)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	other := errors.New("disk full")
+	assert.Equal(t, other, notFound(other, "no note"))
+
+	err = duplicate(fmt.Errorf("%w: %v", ErrDuplicateTitle, other), "x")
+	assert.EqualError(t, err, `a note titled "x" already exists`)
+	assert.ErrorIs(t, err, ErrDuplicateTitle)
+	assert.NotErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,94 @@
+package picker
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ktr0731/go-fuzzyfinder"
+	"golang.org/x/term"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+var (
+	ErrAborted   = errors.New("no note was selected")
+	ErrNoNotes   = errors.New("there are no notes to choose from")
+	ErrBadChoice = errors.New("invalid selection")
+)
+
+// Pick asks the user to choose one of the notes. When out or errOut is a terminal an
+// interactive fuzzy finder is used on the terminal, with preview rendering the description
+// shown alongside the list, so that out can still be captured (e.g. by a shell widget).
+// Otherwise it falls back to a numbered prompt on errOut, reading the choice from in.
+func Pick(ns []notes.Note, preview func(notes.Note) string, in io.Reader, out, errOut io.Writer) (*notes.Note, error) {
+	if len(ns) == 0 {
+		return nil, ErrNoNotes
+	}
+
+	if isTerminal(out) || isTerminal(errOut) {
+		return find(ns, preview)
+	}
+
+	return prompt(ns, in, errOut)
+}
+
+// isTerminal reports whether w is a terminal, which is never the case for buffers.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func find(ns []notes.Note, preview func(notes.Note) string) (*notes.Note, error) {
+	i, err := fuzzyfinder.Find(ns,
+		func(i int) string {
+			return ns[i].Title
+		},
+		fuzzyfinder.WithPromptString("note> "),
+		fuzzyfinder.WithPreviewWindow(func(i, _, _ int) string {
+			if i == -1 {
+				return ""
+			}
+
+			return preview(ns[i])
+		}),
+	)
+	if err != nil {
+		if errors.Is(err, fuzzyfinder.ErrAbort) {
+			return nil, ErrAborted
+		}
+
+		return nil, err
+	}
+
+	return &ns[i], nil
+}
+
+func prompt(ns []notes.Note, r io.Reader, w io.Writer) (*notes.Note, error) {
+	for i, n := range ns {
+		fmt.Fprintf(w, "%3d) %s\n", i+1, n.Title)
+	}
+
+	fmt.Fprintf(w, "Select a note [1-%d]: ", len(ns))
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return nil, ErrAborted
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, ErrAborted
+	}
+
+	i, err := strconv.Atoi(line)
+	if err != nil || i < 1 || i > len(ns) {
+		return nil, fmt.Errorf("%w: %q", ErrBadChoice, line)
+	}
+
+	return &ns[i-1], nil
+}
@@ -0,0 +1,63 @@
+package picker
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+func TestPrompt(t *testing.T) {
+	ns := []notes.Note{
+		{ID: 4, Title: "first"},
+		{ID: 9, Title: "second"},
+	}
+
+	t.Run("should list the notes and return the chosen one", func(t *testing.T) {
+		var out bytes.Buffer
+
+		n, err := prompt(ns, strings.NewReader("2\n"), &out)
+		require.NoError(t, err)
+		assert.Equal(t, 9, n.ID)
+		assert.Equal(t, "  1) first\n  2) second\nSelect a note [1-2]: ", out.String())
+	})
+
+	t.Run("should accept a choice without a trailing newline", func(t *testing.T) {
+		n, err := prompt(ns, strings.NewReader("1"), &bytes.Buffer{})
+		require.NoError(t, err)
+		assert.Equal(t, 4, n.ID)
+	})
+
+	t.Run("should return an error for an out of range choice", func(t *testing.T) {
+		n, err := prompt(ns, strings.NewReader("3\n"), &bytes.Buffer{})
+		assert.Nil(t, n)
+		assert.ErrorIs(t, err, ErrBadChoice)
+	})
+
+	t.Run("should abort when nothing is chosen", func(t *testing.T) {
+		n, err := prompt(ns, strings.NewReader(""), &bytes.Buffer{})
+		assert.Nil(t, n)
+		assert.ErrorIs(t, err, ErrAborted)
+	})
+}
+
+func TestPick(t *testing.T) {
+	t.Run("should prompt when the output isn't a terminal", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+
+		n, err := Pick([]notes.Note{{ID: 4, Title: "first"}}, nil, strings.NewReader("1\n"), &out, &errOut)
+		require.NoError(t, err)
+		assert.Equal(t, 4, n.ID)
+		assert.Empty(t, out.String())
+		assert.Contains(t, errOut.String(), "  1) first\n")
+	})
+
+	t.Run("should return an error when there are no notes", func(t *testing.T) {
+		_, err := Pick(nil, nil, strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{})
+		assert.ErrorIs(t, err, ErrNoNotes)
+	})
+}
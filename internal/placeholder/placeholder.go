@@ -0,0 +1,157 @@
+// Package placeholder finds and fills in the placeholders in note descriptions, which are
+// written as {{name}} or <name>, optionally with a default value, e.g. {{port:8080}}.
+package placeholder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var ErrMissingValue = errors.New("missing value for placeholder")
+
+var pattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][\w.-]*)\s*(?::([^{}]*))?\}\}|<([A-Za-z_][\w.-]*)(?::([^<>]*))?>`)
+
+type Placeholder struct {
+	Name       string
+	Default    string
+	HasDefault bool
+}
+
+// Parse returns the placeholders in s, in the order they first appear. If a placeholder is
+// used more than once, the first default given for it is used.
+func Parse(s string) []Placeholder {
+	out := make([]Placeholder, 0)
+	index := make(map[string]int)
+
+	for _, m := range pattern.FindAllStringSubmatchIndex(s, -1) {
+		p := placeholderAt(s, m)
+
+		i, ok := index[p.Name]
+		if !ok {
+			index[p.Name] = len(out)
+			out = append(out, p)
+			continue
+		}
+
+		if !out[i].HasDefault && p.HasDefault {
+			out[i].Default, out[i].HasDefault = p.Default, true
+		}
+	}
+
+	return out
+}
+
+// Names returns the names of the placeholders in s, in the order they first appear.
+func Names(s string) []string {
+	ps := Parse(s)
+
+	out := make([]string, len(ps))
+	for i, p := range ps {
+		out[i] = p.Name
+	}
+
+	return out
+}
+
+func placeholderAt(s string, m []int) Placeholder {
+	// The submatches for {{name:default}} are 1 and 2, and for <name:default> are 3 and 4
+	group := 1
+	if m[2] == -1 {
+		group = 3
+	}
+
+	p := Placeholder{Name: s[m[2*group]:m[2*group+1]]}
+
+	if start := m[2*group+2]; start != -1 {
+		p.Default, p.HasDefault = strings.TrimSpace(s[start:m[2*group+3]]), true
+	}
+
+	return p
+}
+
+// Render replaces the placeholders in s with their values, falling back to their defaults.
+// ErrMissingValue is returned if a placeholder has neither.
+func Render(s string, values map[string]string) (string, error) {
+	var err error
+
+	out := pattern.ReplaceAllStringFunc(s, func(match string) string {
+		p := placeholderAt(match, pattern.FindStringSubmatchIndex(match))
+
+		if v, ok := values[p.Name]; ok {
+			return v
+		}
+
+		if p.HasDefault {
+			return p.Default
+		}
+
+		if err == nil {
+			err = fmt.Errorf("%w %q", ErrMissingValue, p.Name)
+		}
+
+		return match
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return out, nil
+}
+
+// Prompt asks for the value of each placeholder that isn't already in values, writing the
+// prompts to w and reading a line for each from r. An empty answer uses the default, if
+// there is one. The values are returned with the answers added.
+func Prompt(ps []Placeholder, values map[string]string, r io.Reader, w io.Writer) (map[string]string, error) {
+	out := make(map[string]string, len(ps))
+	for k, v := range values {
+		out[k] = v
+	}
+
+	br := bufio.NewReader(r)
+
+	for _, p := range ps {
+		if _, ok := out[p.Name]; ok {
+			continue
+		}
+
+		if p.HasDefault {
+			fmt.Fprintf(w, "%s [%s]: ", p.Name, p.Default)
+		} else {
+			fmt.Fprintf(w, "%s: ", p.Name)
+		}
+
+		line, err := br.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			return nil, fmt.Errorf("%w %q: %v", ErrMissingValue, p.Name, err)
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" && p.HasDefault {
+			line = p.Default
+		}
+
+		out[p.Name] = line
+	}
+
+	return out, nil
+}
+
+// ParseValues parses name=value pairs, as given to the --set flag.
+func ParseValues(pairs []string) (map[string]string, error) {
+	out := make(map[string]string, len(pairs))
+
+	for _, pair := range pairs {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid value %q, expected name=value", pair)
+		}
+
+		out[name] = value
+	}
+
+	return out, nil
+}
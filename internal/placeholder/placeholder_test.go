@@ -0,0 +1,66 @@
+package placeholder
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	ps := Parse("kubectl -n <namespace> port-forward {{ pod }} {{port:8080}}:<port> -- {{namespace:default}}")
+
+	assert.Equal(t, []Placeholder{
+		{Name: "namespace", Default: "default", HasDefault: true},
+		{Name: "pod"},
+		{Name: "port", Default: "8080", HasDefault: true},
+	}, ps)
+
+	assert.Equal(t, []string{"namespace", "pod", "port"}, Names("kubectl -n <namespace> port-forward {{ pod }} {{port:8080}}:<port>"))
+	assert.Equal(t, []string{}, Names("no placeholders <here or {{ there"))
+}
+
+func TestRender(t *testing.T) {
+	t.Run("should use the values and then the defaults", func(t *testing.T) {
+		out, err := Render("kubectl -n <namespace> logs {{pod}} --tail {{lines:100}}", map[string]string{
+			"namespace": "kube-system",
+			"pod":       "coredns",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "kubectl -n kube-system logs coredns --tail 100", out)
+	})
+
+	t.Run("should return an error when a value is missing", func(t *testing.T) {
+		_, err := Render("kubectl logs {{pod}}", nil)
+		assert.ErrorIs(t, err, ErrMissingValue)
+		assert.ErrorContains(t, err, `"pod"`)
+	})
+}
+
+func TestPrompt(t *testing.T) {
+	t.Run("should ask for each missing value", func(t *testing.T) {
+		var out bytes.Buffer
+
+		values, err := Prompt(Parse("{{namespace}} {{pod}} {{port:8080}}"), map[string]string{"namespace": "default"}, strings.NewReader("coredns\n\n"), &out)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]string{"namespace": "default", "pod": "coredns", "port": "8080"}, values)
+		assert.Equal(t, "pod: port [8080]: ", out.String())
+	})
+
+	t.Run("should return an error when the input ends early", func(t *testing.T) {
+		_, err := Prompt(Parse("{{namespace}} {{pod}}"), nil, strings.NewReader("default\n"), &bytes.Buffer{})
+		assert.ErrorIs(t, err, ErrMissingValue)
+	})
+}
+
+func TestParseValues(t *testing.T) {
+	values, err := ParseValues([]string{"pod=coredns", "selector=app=web,tier=frontend"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"pod": "coredns", "selector": "app=web,tier=frontend"}, values)
+
+	_, err = ParseValues([]string{"pod"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,468 @@
+// Package remote is a notes.NoteReaderWriter that stores notes on a copy-paste-notes server,
+// using the REST API served by the serve command.
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+var (
+	// ErrUnreachable is returned when the server can't be reached, after any retries.
+	ErrUnreachable = errors.New("unable to reach server")
+	// ErrUnauthorized is returned when the server rejects the token.
+	ErrUnauthorized = errors.New("the server rejected the token")
+)
+
+// The defaults for the zero values in Config.
+const (
+	DefaultTimeout       = 10 * time.Second
+	DefaultRetries       = 3
+	DefaultRetryInterval = 250 * time.Millisecond
+)
+
+type Config struct {
+	// URL is the address of the server, e.g. http://notes.internal:8080.
+	URL string
+	// Token is sent as a bearer token, and must match the server's serve.token.
+	Token string
+	// Timeout limits each request, including reading the response.
+	Timeout time.Duration
+	// Retries is the number of times a request is retried after a transient failure, such as
+	// the server being unreachable or returning a 502, 503 or 504.
+	Retries int
+	// RetryInterval is the wait before the first retry, which grows for each retry after it.
+	RetryInterval time.Duration
+}
+
+type Client struct {
+	base          *url.URL
+	token         string
+	http          *http.Client
+	retries       int
+	retryInterval time.Duration
+}
+
+var _ notes.NoteReaderWriter = (*Client)(nil)
+
+func New(cfg Config) (*Client, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("a server url is required")
+	}
+
+	base, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server url: %w", err)
+	}
+
+	if (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid server url %q, expected e.g. http://localhost:8080", cfg.URL)
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	if cfg.Retries == 0 {
+		cfg.Retries = DefaultRetries
+	}
+
+	if cfg.RetryInterval == 0 {
+		cfg.RetryInterval = DefaultRetryInterval
+	}
+
+	return &Client{
+		base:          base,
+		token:         cfg.Token,
+		http:          &http.Client{Timeout: cfg.Timeout},
+		retries:       cfg.Retries,
+		retryInterval: cfg.RetryInterval,
+	}, nil
+}
+
+// Close closes any idle connections to the server.
+func (c *Client) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+// transportError is a request that failed without a response, e.g. because the server is
+// down or didn't respond in time.
+type transportError struct {
+	err error
+}
+
+func (e *transportError) Error() string {
+	return e.err.Error()
+}
+
+// statusError is a response with an unexpected status code.
+type statusError struct {
+	code    int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("server returned %d %s: %s", e.code, http.StatusText(e.code), e.message)
+}
+
+// do sends a request to the path, encoding body as JSON if it isn't nil and decoding the
+// response into out if it isn't nil. Transient failures are retried, although requests that
+// aren't idempotent are only retried if they couldn't be sent.
+func (c *Client) do(method, path string, query url.Values, body, out any) error {
+	var b []byte
+
+	if body != nil {
+		var err error
+
+		b, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("unable to encode request: %w", err)
+		}
+	}
+
+	u := c.base.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = c.retryInterval
+	bo.MaxElapsedTime = 0
+
+	return backoff.Retry(func() error {
+		err := c.send(method, u.String(), b, out)
+
+		var (
+			se *statusError
+			te *transportError
+		)
+
+		switch {
+		case err == nil:
+			return nil
+		case errors.As(err, &se) && idempotent(method) && temporary(se.code):
+			return err
+		case errors.As(err, &te):
+			err = fmt.Errorf("%w at %s: %v", ErrUnreachable, c.base, te.err)
+			if notSent(te.err) || idempotent(method) {
+				return err
+			}
+		}
+
+		return backoff.Permanent(err)
+	}, backoff.WithMaxRetries(bo, uint64(c.retries)))
+}
+
+func (c *Client) send(method, u string, body []byte, out any) error {
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return &transportError{err: err}
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		return responseError(res)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("unable to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// responseError turns an error response into the error the other stores would return.
+func responseError(res *http.Response) error {
+	var e struct {
+		Error string `json:"error"`
+	}
+
+	b, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err := json.Unmarshal(b, &e); err != nil || e.Error == "" {
+		e.Error = strings.TrimSpace(string(b))
+	}
+
+	switch res.StatusCode {
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", notes.ErrNotFound, e.Error)
+	case http.StatusConflict:
+		return fmt.Errorf("%w: %s", notes.ErrDuplicateTitle, e.Error)
+	case http.StatusBadRequest:
+		return fmt.Errorf("%w: %s", notes.ErrInvalidNote, e.Error)
+	case http.StatusUnauthorized:
+		return fmt.Errorf("%w, check db.token", ErrUnauthorized)
+	default:
+		return &statusError{code: res.StatusCode, message: e.Error}
+	}
+}
+
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete
+}
+
+func temporary(code int) bool {
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable || code == http.StatusGatewayTimeout
+}
+
+// notSent reports whether a request failed before it reached the server, so it's safe to
+// retry whatever it was.
+func notSent(err error) bool {
+	var oe *net.OpError
+	return errors.As(err, &oe) && oe.Op == "dial"
+}
+
+func notePath(id int) string {
+	return fmt.Sprintf("notes/%d", id)
+}
+
+func (c *Client) ListNotes() ([]notes.Note, error) {
+	out := make([]notes.Note, 0)
+	if err := c.do(http.MethodGet, "notes", nil, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// SearchNotes searches the notes on the server. The server doesn't send highlights, so they're
+// added here by marking each term of the query.
+func (c *Client) SearchNotes(query string) ([]notes.SearchResult, error) {
+	out := make([]notes.SearchResult, 0)
+	if err := c.do(http.MethodGet, "notes", url.Values{"q": {query}}, nil, &out); err != nil {
+		return nil, err
+	}
+
+	terms := strings.Fields(query)
+	for i, t := range terms {
+		terms[i] = regexp.QuoteMeta(t)
+	}
+
+	re := regexp.MustCompile("(?i)" + strings.Join(terms, "|"))
+	highlight := notes.HighlightStart + "$0" + notes.HighlightEnd
+
+	for i := range out {
+		out[i].TitleHighlight = re.ReplaceAllString(out[i].Title, highlight)
+		out[i].DescriptionHighlight = re.ReplaceAllString(out[i].Description, highlight)
+	}
+
+	return out, nil
+}
+
+func (c *Client) get(path string, query url.Values) (*notes.Note, error) {
+	var n notes.Note
+	if err := c.do(http.MethodGet, path, query, nil, &n); err != nil {
+		return nil, err
+	}
+
+	// Placeholders aren't stored, they're only filled in for output
+	n.Placeholders = nil
+
+	return &n, nil
+}
+
+func (c *Client) GetNoteByID(id int) (*notes.Note, error) {
+	return c.get(notePath(id), nil)
+}
+
+func (c *Client) GetNoteByTitle(title string) (*notes.Note, error) {
+	return c.get("notes", url.Values{"title": {title}})
+}
+
+// noteRequest is the body sent to create and update notes.
+type noteRequest struct {
+	Title           string     `json:"title,omitempty"`
+	Description     string     `json:"description,omitempty"`
+	Tags            []string   `json:"tags"`
+	CreateTimestamp *time.Time `json:"createTimestamp,omitempty"`
+}
+
+// InsertNote creates a note on the server. The server sets the create timestamp if it's zero.
+func (c *Client) InsertNote(n notes.Note) (int, error) {
+	// Tags are checked here so that, as with the other stores, invalid tags are reported with
+	// notes.ErrInvalidTag
+	if _, err := notes.NormaliseTags(n.Tags); err != nil {
+		return 0, err
+	}
+
+	req := noteRequest{Title: n.Title, Description: n.Description, Tags: n.Tags}
+	if !n.CreateTimestamp.IsZero() {
+		req.CreateTimestamp = &n.CreateTimestamp
+	}
+
+	var created notes.Note
+	if err := c.do(http.MethodPost, "notes", nil, req, &created); err != nil {
+		return 0, err
+	}
+
+	return created.ID, nil
+}
+
+func (c *Client) UpdateNote(id int, n notes.Note) (int64, error) {
+	if _, err := notes.NormaliseTags(n.Tags); err != nil {
+		return 0, err
+	}
+
+	// Tags are left out when they're nil, so that they aren't changed
+	var body any = noteRequest{Title: n.Title, Description: n.Description, Tags: n.Tags}
+	if n.Tags == nil {
+		body = struct {
+			Title       string `json:"title,omitempty"`
+			Description string `json:"description,omitempty"`
+		}{n.Title, n.Description}
+	}
+
+	err := c.do(http.MethodPatch, notePath(id), nil, body, nil)
+	if errors.Is(err, notes.ErrNotFound) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return 1, nil
+}
+
+// DeleteNote moves a note to the trash.
+func (c *Client) DeleteNote(id int) error {
+	return c.do(http.MethodDelete, notePath(id), nil, nil, nil)
+}
+
+func (c *Client) TagNote(id int, tags []string) error {
+	if _, err := notes.NormaliseTags(tags); err != nil {
+		return err
+	}
+
+	return c.do(http.MethodPost, notePath(id)+"/tags", nil, map[string][]string{"tags": tags}, nil)
+}
+
+func (c *Client) UntagNote(id int, tags []string) error {
+	if _, err := notes.NormaliseTags(tags); err != nil {
+		return err
+	}
+
+	return c.do(http.MethodDelete, notePath(id)+"/tags", url.Values{"tag": tags}, nil, nil)
+}
+
+func (c *Client) MarkNoteUsed(id int) error {
+	return c.do(http.MethodPost, notePath(id)+"/used", nil, nil, nil)
+}
+
+func (c *Client) ListNoteRevisions(id int) ([]notes.Revision, error) {
+	out := make([]notes.Revision, 0)
+
+	err := c.do(http.MethodGet, notePath(id)+"/revisions", nil, nil, &out)
+	if errors.Is(err, notes.ErrNotFound) {
+		return out, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (c *Client) GetNoteRevision(id int, revision int) (*notes.Revision, error) {
+	var r notes.Revision
+	if err := c.do(http.MethodGet, fmt.Sprintf("%s/revisions/%d", notePath(id), revision), nil, nil, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+func (c *Client) RestoreNoteRevision(id int, revision int) (int64, error) {
+	if err := c.do(http.MethodPost, fmt.Sprintf("%s/revisions/%d/restore", notePath(id), revision), nil, nil, nil); err != nil {
+		return 0, err
+	}
+
+	return 1, nil
+}
+
+func (c *Client) ListTrashedNotes() ([]notes.Note, error) {
+	out := make([]notes.Note, 0)
+	if err := c.do(http.MethodGet, "trash", nil, nil, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func (c *Client) RestoreNote(id int) error {
+	return c.do(http.MethodPost, fmt.Sprintf("trash/%d/restore", id), nil, nil, nil)
+}
+
+func (c *Client) PurgeNote(id int) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("trash/%d", id), nil, nil, nil)
+}
+
+func (c *Client) EmptyTrash(before time.Time) (int64, error) {
+	query := url.Values{}
+	if !before.IsZero() {
+		query.Set("before", notes.FormatTimestamp(before))
+	}
+
+	var res struct {
+		Deleted int64 `json:"deleted"`
+	}
+
+	if err := c.do(http.MethodDelete, "trash", query, nil, &res); err != nil {
+		return 0, err
+	}
+
+	return res.Deleted, nil
+}
+
+func (c *Client) ImportNotes(ns []notes.Note, onConflict notes.ConflictPolicy) (notes.ImportResult, error) {
+	var res notes.ImportResult
+
+	if err := onConflict.Validate(); err != nil {
+		return res, err
+	}
+
+	for _, n := range ns {
+		if _, err := notes.NormaliseTags(n.Tags); err != nil {
+			return res, fmt.Errorf("note %q: %w", n.Title, err)
+		}
+	}
+
+	if ns == nil {
+		ns = []notes.Note{}
+	}
+
+	if err := c.do(http.MethodPost, "import", url.Values{"onConflict": {string(onConflict)}}, ns, &res); err != nil {
+		return notes.ImportResult{}, err
+	}
+
+	return res, nil
+}
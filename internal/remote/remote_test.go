@@ -0,0 +1,117 @@
+package remote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+	"github.com/simondrake/copy-paste-notes/internal/notes/notestest"
+	"github.com/simondrake/copy-paste-notes/internal/server"
+)
+
+const token = "secret"
+
+func TestConformance(t *testing.T) {
+	notestest.Conformance(t, func(t *testing.T) notes.NoteReaderWriter {
+		srv := httptest.NewServer(server.New(notes.New(notestest.New()), token, nil))
+		t.Cleanup(srv.Close)
+
+		c, err := New(Config{URL: srv.URL, Token: token})
+		require.NoError(t, err)
+
+		return c
+	})
+}
+
+func TestNew(t *testing.T) {
+	for _, u := range []string{"", "localhost:8080", "ftp://localhost", "http://"} {
+		_, err := New(Config{URL: u})
+		assert.Error(t, err, u)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	var attempts int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = w.Write([]byte(`[{"id": 1, "title": "t", "description": "d"}]`))
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := New(Config{URL: srv.URL, Token: token, RetryInterval: time.Millisecond})
+	require.NoError(t, err)
+
+	t.Run("should retry idempotent requests", func(t *testing.T) {
+		ns, err := c.ListNotes()
+		require.NoError(t, err)
+		assert.Len(t, ns, 1)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("should not retry other requests once they've been sent", func(t *testing.T) {
+		atomic.StoreInt32(&attempts, 0)
+
+		err := c.MarkNoteUsed(1)
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	})
+
+	t.Run("should give up after the retries", func(t *testing.T) {
+		atomic.StoreInt32(&attempts, -10)
+
+		_, err := c.ListNotes()
+		assert.ErrorContains(t, err, "503")
+		assert.Equal(t, int32(-6), atomic.LoadInt32(&attempts))
+	})
+}
+
+func TestErrors(t *testing.T) {
+	t.Run("should report an unreachable server", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+
+		c, err := New(Config{URL: srv.URL, Token: token, RetryInterval: time.Millisecond})
+		require.NoError(t, err)
+
+		_, err = c.InsertNote(notes.Note{Title: "t", Description: "d"})
+		assert.ErrorIs(t, err, ErrUnreachable)
+	})
+
+	t.Run("should time out", func(t *testing.T) {
+		block := make(chan struct{})
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-block
+		}))
+		t.Cleanup(srv.Close)
+		t.Cleanup(func() { close(block) })
+
+		c, err := New(Config{URL: srv.URL, Token: token, Timeout: 10 * time.Millisecond, Retries: 1, RetryInterval: time.Millisecond})
+		require.NoError(t, err)
+
+		_, err = c.GetNoteByID(1)
+		assert.ErrorIs(t, err, ErrUnreachable)
+	})
+
+	t.Run("should report a wrong token", func(t *testing.T) {
+		srv := httptest.NewServer(server.New(notes.New(notestest.New()), token, nil))
+		t.Cleanup(srv.Close)
+
+		c, err := New(Config{URL: srv.URL, Token: "wrong"})
+		require.NoError(t, err)
+
+		_, err = c.ListNotes()
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+}
@@ -0,0 +1,462 @@
+// Package rpc serves the notes over JSON-RPC 2.0, with one message per line, for editors and
+// other tools that would rather keep a process running than parse command output.
+//
+// The methods, which take their params as an object, are:
+//
+//	list    {"tags": [...], "matchAll": bool, "sort": "id", "limit": int}        => [note]
+//	get     {"id": int} or {"title": string}                                     => note
+//	search  {"query": string}                                                    => [note]
+//	create  {"title": string, "description": string, "tags": [...]}              => note
+//	update  {"id": int, "title": string, "description": string, "tags": [...]}   => note
+//	delete  {"id": int}                                                          => null
+//	render  {"id": int} or {"title": string}, "values": {...}, "raw": bool       => string
+//
+// Notes are encoded in the same shape as "get --format json". Whenever a note is created,
+// updated or deleted, a "noteChanged" notification is sent with {"id": int, "change":
+// "created" | "updated" | "deleted"}.
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+	"github.com/simondrake/copy-paste-notes/internal/placeholder"
+)
+
+// The error codes, which won't change. The codes from -32700 to -32600 are defined by the
+// JSON-RPC 2.0 specification, and the rest by this package.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+
+	// CodeNotFound is returned when there's no note with the given ID or title.
+	CodeNotFound = -32001
+	// CodeDuplicateTitle is returned when another note already has the title.
+	CodeDuplicateTitle = -32002
+	// CodeInvalidNote is returned when a note fails validation, e.g. it has an empty title.
+	CodeInvalidNote = -32003
+	// CodeMissingValue is returned by render when a placeholder has no value or default.
+	CodeMissingValue = -32004
+)
+
+// maxMessageSize is the longest line that's read.
+const maxMessageSize = 10 << 20
+
+// Error is the error object of a response.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	// ID is nil for notifications, which aren't responded to
+	ID json.RawMessage `json:"id"`
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      json.RawMessage  `json:"id"`
+	Result  *json.RawMessage `json:"result,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+}
+
+type notification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+// Change describes a note that was changed, as the params of a "noteChanged" notification.
+type Change struct {
+	ID     int    `json:"id"`
+	Change string `json:"change"`
+}
+
+// Server handles JSON-RPC requests with a notes client.
+type Server struct {
+	client  *notes.Client
+	methods map[string]func(json.RawMessage) (any, []Change, error)
+}
+
+func New(client *notes.Client) *Server {
+	s := &Server{client: client}
+
+	s.methods = map[string]func(json.RawMessage) (any, []Change, error){
+		"list":   s.list,
+		"get":    s.get,
+		"search": s.search,
+		"create": s.create,
+		"update": s.update,
+		"delete": s.delete,
+		"render": s.render,
+	}
+
+	return s
+}
+
+// Serve handles the requests read from r, one per line, until it reaches the end of r.
+// Responses and notifications are written to w, one per line.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+
+	e := json.NewEncoder(w)
+
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		res, changes := s.handleMessage(line)
+
+		if res != nil {
+			if err := e.Encode(res); err != nil {
+				return fmt.Errorf("unable to write response: %w", err)
+			}
+		}
+
+		for _, c := range changes {
+			if err := e.Encode(notification{JSONRPC: "2.0", Method: "noteChanged", Params: c}); err != nil {
+				return fmt.Errorf("unable to write notification: %w", err)
+			}
+		}
+	}
+
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("unable to read request: %w", err)
+	}
+
+	return nil
+}
+
+// handleMessage handles a request or a batch of them, returning what should be written in
+// response, if anything, and the notes that were changed.
+func (s *Server) handleMessage(msg []byte) (any, []Change) {
+	if msg[0] != '[' {
+		var req request
+		if err := json.Unmarshal(msg, &req); err != nil {
+			return errorResponse(nil, &Error{Code: CodeParseError, Message: err.Error()}), nil
+		}
+
+		res, changes := s.handle(req)
+		if res == nil {
+			// Don't return a nil *response, which isn't a nil any
+			return nil, changes
+		}
+
+		return res, changes
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(msg, &batch); err != nil {
+		return errorResponse(nil, &Error{Code: CodeParseError, Message: err.Error()}), nil
+	}
+
+	if len(batch) == 0 {
+		return errorResponse(nil, &Error{Code: CodeInvalidRequest, Message: "empty batch"}), nil
+	}
+
+	var (
+		responses []*response
+		changes   []Change
+	)
+
+	for _, m := range batch {
+		var req request
+		if err := json.Unmarshal(m, &req); err != nil {
+			responses = append(responses, errorResponse(nil, &Error{Code: CodeInvalidRequest, Message: err.Error()}))
+			continue
+		}
+
+		res, c := s.handle(req)
+		if res != nil {
+			responses = append(responses, res)
+		}
+
+		changes = append(changes, c...)
+	}
+
+	// A batch of notifications has no response at all
+	if len(responses) == 0 {
+		return nil, changes
+	}
+
+	return responses, changes
+}
+
+func (s *Server) handle(req request) (*response, []Change) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, &Error{Code: CodeInvalidRequest, Message: `requests must have "jsonrpc": "2.0" and a method`}), nil
+	}
+
+	method, ok := s.methods[req.Method]
+	if !ok {
+		if req.ID == nil {
+			return nil, nil
+		}
+
+		return errorResponse(req.ID, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}), nil
+	}
+
+	result, changes, err := method(req.Params)
+
+	if req.ID == nil {
+		return nil, changes
+	}
+
+	if err != nil {
+		return errorResponse(req.ID, toError(err)), changes
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(req.ID, &Error{Code: CodeInternalError, Message: err.Error()}), changes
+	}
+
+	raw := json.RawMessage(b)
+
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: &raw}, changes
+}
+
+func errorResponse(id json.RawMessage, err *Error) *response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+
+	return &response{JSONRPC: "2.0", ID: id, Error: err}
+}
+
+// toError returns the error object for an error returned by a method.
+func toError(err error) *Error {
+	var e *Error
+
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, notes.ErrNotFound):
+		return &Error{Code: CodeNotFound, Message: err.Error()}
+	case errors.Is(err, notes.ErrDuplicateTitle):
+		return &Error{Code: CodeDuplicateTitle, Message: err.Error()}
+	case errors.Is(err, notes.ErrInvalidNote), errors.Is(err, notes.ErrInvalidTag):
+		return &Error{Code: CodeInvalidNote, Message: err.Error()}
+	case errors.Is(err, placeholder.ErrMissingValue):
+		return &Error{Code: CodeMissingValue, Message: err.Error()}
+	default:
+		return &Error{Code: CodeInternalError, Message: err.Error()}
+	}
+}
+
+// decode decodes the params, which must be an object if they're given, into v.
+func decode(params json.RawMessage, v any) error {
+	params = bytes.TrimSpace(params)
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		return nil
+	}
+
+	d := json.NewDecoder(bytes.NewReader(params))
+	d.DisallowUnknownFields()
+
+	if err := d.Decode(v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+
+	return nil
+}
+
+func invalidParams(format string, args ...any) error {
+	return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+type listParams struct {
+	Tags     []string `json:"tags"`
+	MatchAll bool     `json:"matchAll"`
+	Sort     string   `json:"sort"`
+	Limit    int      `json:"limit"`
+}
+
+func (s *Server) list(params json.RawMessage) (any, []Change, error) {
+	p := listParams{Sort: string(notes.SortID)}
+	if err := decode(params, &p); err != nil {
+		return nil, nil, err
+	}
+
+	ns, err := s.client.ListByTags(p.Tags, p.MatchAll)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := notes.Sort(ns, notes.SortOrder(p.Sort), time.Now()); err != nil {
+		return nil, nil, invalidParams("%s", err)
+	}
+
+	if p.Limit > 0 && len(ns) > p.Limit {
+		ns = ns[:p.Limit]
+	}
+
+	return ns, nil, nil
+}
+
+// noteParams identify a note by either its ID or title.
+type noteParams struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+func (s *Server) note(p noteParams) (*notes.Note, error) {
+	switch {
+	case p.ID != 0 && p.Title != "":
+		return nil, invalidParams("only one of id or title can be given")
+	case p.ID != 0:
+		return s.client.GetByID(p.ID)
+	case p.Title != "":
+		return s.client.GetByTitle(p.Title)
+	default:
+		return nil, invalidParams("id or title is required")
+	}
+}
+
+func (s *Server) get(params json.RawMessage) (any, []Change, error) {
+	var p noteParams
+	if err := decode(params, &p); err != nil {
+		return nil, nil, err
+	}
+
+	n, err := s.note(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	n.Placeholders = placeholder.Names(n.Description)
+
+	return n, nil, nil
+}
+
+func (s *Server) search(params json.RawMessage) (any, []Change, error) {
+	var p struct {
+		Query string `json:"query"`
+	}
+
+	if err := decode(params, &p); err != nil {
+		return nil, nil, err
+	}
+
+	if p.Query == "" {
+		return nil, nil, invalidParams("query is required")
+	}
+
+	rs, err := s.client.Search(p.Query)
+
+	return rs, nil, err
+}
+
+type noteFields struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+func (s *Server) create(params json.RawMessage) (any, []Change, error) {
+	var p noteFields
+	if err := decode(params, &p); err != nil {
+		return nil, nil, err
+	}
+
+	id, err := s.client.Create(notes.Note{Title: p.Title, Description: p.Description, Tags: p.Tags})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes := []Change{{ID: id, Change: "created"}}
+
+	n, err := s.client.GetByID(id)
+
+	return n, changes, err
+}
+
+func (s *Server) update(params json.RawMessage) (any, []Change, error) {
+	var p struct {
+		ID int `json:"id"`
+		noteFields
+	}
+
+	if err := decode(params, &p); err != nil {
+		return nil, nil, err
+	}
+
+	if p.ID == 0 {
+		return nil, nil, invalidParams("id is required")
+	}
+
+	if _, err := s.client.Update(p.ID, notes.Note{Title: p.Title, Description: p.Description, Tags: p.Tags}); err != nil {
+		return nil, nil, err
+	}
+
+	changes := []Change{{ID: p.ID, Change: "updated"}}
+
+	n, err := s.client.GetByID(p.ID)
+
+	return n, changes, err
+}
+
+func (s *Server) delete(params json.RawMessage) (any, []Change, error) {
+	var p struct {
+		ID int `json:"id"`
+	}
+
+	if err := decode(params, &p); err != nil {
+		return nil, nil, err
+	}
+
+	if p.ID == 0 {
+		return nil, nil, invalidParams("id is required")
+	}
+
+	if err := s.client.Delete(p.ID); err != nil {
+		return nil, nil, err
+	}
+
+	return nil, []Change{{ID: p.ID, Change: "deleted"}}, nil
+}
+
+func (s *Server) render(params json.RawMessage) (any, []Change, error) {
+	var p struct {
+		noteParams
+		Values map[string]string `json:"values"`
+		Raw    bool              `json:"raw"`
+	}
+
+	if err := decode(params, &p); err != nil {
+		return nil, nil, err
+	}
+
+	n, err := s.note(p.noteParams)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	description := n.Description
+	if !p.Raw {
+		description = notes.ParseNewlines(description)
+	}
+
+	out, err := placeholder.Render(description, p.Values)
+
+	return out, nil, err
+}
@@ -0,0 +1,224 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+	"github.com/simondrake/copy-paste-notes/internal/notes/notestest"
+)
+
+func newServer() *Server {
+	return New(notes.New(notestest.New(
+		notes.Note{Title: "pods", Description: `kubectl get pods -n {{namespace:default}}`, Tags: []string{"k8s"}},
+		notes.Note{Title: "logs", Description: `kubectl logs -f\n`, Tags: []string{"k8s", "debug"}},
+	)))
+}
+
+// serve sends the requests to s, one per line, returning the messages written in response.
+func serve(t *testing.T, s *Server, requests ...string) []map[string]any {
+	t.Helper()
+
+	var out bytes.Buffer
+	require.NoError(t, s.Serve(strings.NewReader(strings.Join(requests, "\n")), &out))
+
+	var msgs []map[string]any
+
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m), line)
+
+		msgs = append(msgs, m)
+	}
+
+	return msgs
+}
+
+// call sends a single request, returning its response.
+func call(t *testing.T, s *Server, method, params string) map[string]any {
+	t.Helper()
+
+	msgs := serve(t, s, `{"jsonrpc": "2.0", "id": 1, "method": "`+method+`", "params": `+params+`}`)
+	require.NotEmpty(t, msgs)
+
+	return msgs[0]
+}
+
+func errorCode(t *testing.T, res map[string]any) int {
+	t.Helper()
+
+	e, ok := res["error"].(map[string]any)
+	require.True(t, ok, "expected an error, got %v", res)
+
+	return int(e["code"].(float64))
+}
+
+func TestMethods(t *testing.T) {
+	s := newServer()
+
+	t.Run("list", func(t *testing.T) {
+		res := call(t, s, "list", `{"tags": ["debug"]}`)
+		assert.Equal(t, float64(1), res["id"])
+		assert.Equal(t, "2.0", res["jsonrpc"])
+
+		ns := res["result"].([]any)
+		require.Len(t, ns, 1)
+		assert.Equal(t, "logs", ns[0].(map[string]any)["title"])
+
+		res = call(t, s, "list", `{"sort": "title", "limit": 1}`)
+		ns = res["result"].([]any)
+		require.Len(t, ns, 1)
+		assert.Equal(t, "logs", ns[0].(map[string]any)["title"])
+
+		res = call(t, s, "list", `null`)
+		assert.Len(t, res["result"], 2)
+
+		assert.Equal(t, CodeInvalidParams, errorCode(t, call(t, s, "list", `{"sort": "random"}`)))
+	})
+
+	t.Run("get", func(t *testing.T) {
+		res := call(t, s, "get", `{"id": 1}`)
+		n := res["result"].(map[string]any)
+		assert.Equal(t, "pods", n["title"])
+		assert.Equal(t, []any{"namespace"}, n["placeholders"])
+
+		res = call(t, s, "get", `{"title": "logs"}`)
+		assert.Equal(t, float64(2), res["result"].(map[string]any)["id"])
+
+		assert.Equal(t, CodeNotFound, errorCode(t, call(t, s, "get", `{"id": 99}`)))
+		assert.Equal(t, CodeInvalidParams, errorCode(t, call(t, s, "get", `{}`)))
+		assert.Equal(t, CodeInvalidParams, errorCode(t, call(t, s, "get", `{"id": 1, "title": "pods"}`)))
+		assert.Equal(t, CodeInvalidParams, errorCode(t, call(t, s, "get", `{"name": "pods"}`)))
+		assert.Equal(t, CodeInvalidParams, errorCode(t, call(t, s, "get", `[1]`)))
+	})
+
+	t.Run("search", func(t *testing.T) {
+		res := call(t, s, "search", `{"query": "pods"}`)
+		rs := res["result"].([]any)
+		require.Len(t, rs, 1)
+		assert.Equal(t, "pods", rs[0].(map[string]any)["title"])
+
+		assert.Equal(t, CodeInvalidParams, errorCode(t, call(t, s, "search", `{}`)))
+	})
+
+	t.Run("render", func(t *testing.T) {
+		res := call(t, s, "render", `{"id": 1}`)
+		assert.Equal(t, "kubectl get pods -n default", res["result"])
+
+		res = call(t, s, "render", `{"id": 1, "values": {"namespace": "kube-system"}}`)
+		assert.Equal(t, "kubectl get pods -n kube-system", res["result"])
+
+		res = call(t, s, "render", `{"title": "logs"}`)
+		assert.Equal(t, "kubectl logs -f\n", res["result"])
+
+		res = call(t, s, "render", `{"title": "logs", "raw": true}`)
+		assert.Equal(t, `kubectl logs -f\n`, res["result"])
+	})
+
+	t.Run("create, update and delete", func(t *testing.T) {
+		msgs := serve(t, s, `{"jsonrpc": "2.0", "id": "a", "method": "create", "params": {"title": "events", "description": "kubectl get events {{ns}}", "tags": ["k8s"]}}`)
+		require.Len(t, msgs, 2)
+		assert.Equal(t, "a", msgs[0]["id"])
+		assert.Equal(t, float64(3), msgs[0]["result"].(map[string]any)["id"])
+		assert.Equal(t, map[string]any{"jsonrpc": "2.0", "method": "noteChanged", "params": map[string]any{"id": float64(3), "change": "created"}}, msgs[1])
+
+		assert.Equal(t, CodeMissingValue, errorCode(t, call(t, s, "render", `{"id": 3}`)))
+
+		msgs = serve(t, s, `{"jsonrpc": "2.0", "id": 2, "method": "update", "params": {"id": 3, "description": "kubectl get events -A"}}`)
+		require.Len(t, msgs, 2)
+		assert.Equal(t, "kubectl get events -A", msgs[0]["result"].(map[string]any)["description"])
+		assert.Equal(t, "updated", msgs[1]["params"].(map[string]any)["change"])
+
+		msgs = serve(t, s, `{"jsonrpc": "2.0", "id": 3, "method": "delete", "params": {"id": 3}}`)
+		require.Len(t, msgs, 2)
+		assert.Contains(t, msgs[0], "result")
+		assert.Nil(t, msgs[0]["result"])
+		assert.Equal(t, "deleted", msgs[1]["params"].(map[string]any)["change"])
+
+		assert.Equal(t, CodeNotFound, errorCode(t, call(t, s, "delete", `{"id": 3}`)))
+	})
+
+	t.Run("validation errors", func(t *testing.T) {
+		assert.Equal(t, CodeDuplicateTitle, errorCode(t, call(t, s, "create", `{"title": "pods", "description": "again"}`)))
+		assert.Equal(t, CodeDuplicateTitle, errorCode(t, call(t, s, "update", `{"id": 2, "title": "pods"}`)))
+		assert.Equal(t, CodeInvalidNote, errorCode(t, call(t, s, "create", `{"title": "empty"}`)))
+		assert.Equal(t, CodeInvalidNote, errorCode(t, call(t, s, "create", `{"title": "t", "description": "d", "tags": ["two words"]}`)))
+		assert.Equal(t, CodeInvalidNote, errorCode(t, call(t, s, "update", `{"id": 2}`)))
+		assert.Equal(t, CodeNotFound, errorCode(t, call(t, s, "update", `{"id": 99, "title": "other"}`)))
+		assert.Equal(t, CodeInvalidParams, errorCode(t, call(t, s, "update", `{"title": "other"}`)))
+	})
+}
+
+func TestProtocol(t *testing.T) {
+	t.Run("parse error", func(t *testing.T) {
+		msgs := serve(t, newServer(), `{"jsonrpc": `)
+		require.Len(t, msgs, 1)
+		assert.Nil(t, msgs[0]["id"])
+		assert.Equal(t, CodeParseError, errorCode(t, msgs[0]))
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		msgs := serve(t, newServer(), `{"id": 1, "method": "list"}`, `{"jsonrpc": "2.0", "id": 2}`)
+		require.Len(t, msgs, 2)
+		assert.Equal(t, CodeInvalidRequest, errorCode(t, msgs[0]))
+		assert.Equal(t, float64(2), msgs[1]["id"])
+		assert.Equal(t, CodeInvalidRequest, errorCode(t, msgs[1]))
+	})
+
+	t.Run("method not found", func(t *testing.T) {
+		assert.Equal(t, CodeMethodNotFound, errorCode(t, call(t, newServer(), "purge", `{}`)))
+	})
+
+	t.Run("notifications aren't responded to", func(t *testing.T) {
+		msgs := serve(t, newServer(),
+			`{"jsonrpc": "2.0", "method": "list"}`,
+			`{"jsonrpc": "2.0", "method": "unknown"}`,
+			`{"jsonrpc": "2.0", "method": "delete", "params": {"id": 1}}`,
+		)
+
+		// Only the change notification is sent
+		require.Len(t, msgs, 1)
+		assert.Equal(t, "noteChanged", msgs[0]["method"])
+	})
+
+	t.Run("null id", func(t *testing.T) {
+		msgs := serve(t, newServer(), `{"jsonrpc": "2.0", "id": null, "method": "get", "params": {"id": 1}}`)
+		require.Len(t, msgs, 1)
+		assert.Contains(t, msgs[0], "id")
+		assert.Nil(t, msgs[0]["id"])
+		assert.NotNil(t, msgs[0]["result"])
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, newServer().Serve(strings.NewReader(
+			`[{"jsonrpc": "2.0", "id": 1, "method": "get", "params": {"id": 1}}, {"jsonrpc": "2.0", "method": "list"}, {"jsonrpc": "2.0", "id": 2, "method": "get", "params": {"id": 99}}, 1]`,
+		), &out))
+
+		var res []map[string]any
+		require.NoError(t, json.Unmarshal(out.Bytes(), &res))
+		require.Len(t, res, 3)
+		assert.Equal(t, float64(1), res[0]["id"])
+		assert.Equal(t, CodeNotFound, errorCode(t, res[1]))
+		assert.Equal(t, CodeInvalidRequest, errorCode(t, res[2]))
+
+		msgs := serve(t, newServer(), `[]`)
+		require.Len(t, msgs, 1)
+		assert.Equal(t, CodeInvalidRequest, errorCode(t, msgs[0]))
+
+		assert.Empty(t, serve(t, newServer(), `[{"jsonrpc": "2.0", "method": "list"}]`))
+	})
+
+	t.Run("blank lines are ignored", func(t *testing.T) {
+		assert.Len(t, serve(t, newServer(), "", `{"jsonrpc": "2.0", "id": 1, "method": "list"}`, "  "), 1)
+	})
+}
@@ -0,0 +1,430 @@
+// Package server exposes the notes as a JSON REST API.
+//
+// Notes are encoded in the same shape as "get --format json". Errors are returned as
+// {"error": "message"} with a status code that identifies them: 400 for invalid requests and
+// notes, 401 for a missing or wrong bearer token, 404 for missing notes and 409 for duplicate
+// titles.
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+	"github.com/simondrake/copy-paste-notes/internal/placeholder"
+)
+
+// maxBodySize is the largest request body that's accepted, to stop a client exhausting memory.
+const maxBodySize = 10 << 20
+
+var (
+	errUnauthorized     = errors.New("a valid bearer token is required")
+	errNoRoute          = errors.New("not found")
+	errMethodNotAllowed = errors.New("method not allowed")
+)
+
+type server struct {
+	client *notes.Client
+	token  string
+	log    *log.Logger
+}
+
+// New returns a handler serving the notes in client to requests with the bearer token. Any
+// unexpected errors are written to errLog, if it isn't nil, rather than to the client.
+//
+// The routes are:
+//
+//	GET    /notes                                 list the notes, filtered by ?tag= (with ?match=all) or searched with ?q=
+//	GET    /notes?title=                          get a note by its title
+//	POST   /notes                                 create a note
+//	GET    /notes/{id}                            get a note
+//	PATCH  /notes/{id}                            update the given fields of a note
+//	DELETE /notes/{id}                            move a note to the trash
+//	POST   /notes/{id}/tags                       add tags to a note
+//	DELETE /notes/{id}/tags?tag=                  remove tags from a note
+//	POST   /notes/{id}/used                       record that a note was used
+//	GET    /notes/{id}/revisions                  list the revisions of a note
+//	GET    /notes/{id}/revisions/{rev}            get a revision of a note
+//	POST   /notes/{id}/revisions/{rev}/restore    restore a note to a revision
+//	GET    /trash                                 list the notes in the trash
+//	DELETE /trash?before=                         permanently delete the notes in the trash
+//	POST   /trash/{id}/restore                    restore a note from the trash
+//	DELETE /trash/{id}                            permanently delete a note in the trash
+//	POST   /import?onConflict=                    import notes
+func New(client *notes.Client, token string, errLog *log.Logger) http.Handler {
+	s := &server{client: client, token: token, log: errLog}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/notes", s.handleNotes)
+	mux.HandleFunc("/notes/", s.handleNote)
+	mux.HandleFunc("/trash", s.handleTrash)
+	mux.HandleFunc("/trash/", s.handleTrashedNote)
+	mux.HandleFunc("/import", s.handleImport)
+
+	return s.authenticate(mux)
+}
+
+func (s *server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(auth, "Bearer ")
+
+		if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="copy-paste-notes"`)
+			s.writeError(w, http.StatusUnauthorized, errUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// noteRequest is the body of the requests that create and update notes. Tags are only
+// changed by an update if they're given, and the create timestamp, which defaults to now, can
+// only be set when a note is created.
+type noteRequest struct {
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	Tags            []string  `json:"tags"`
+	CreateTimestamp time.Time `json:"createTimestamp"`
+}
+
+func (nr noteRequest) note() notes.Note {
+	return notes.Note{Title: nr.Title, Description: nr.Description, Tags: nr.Tags, CreateTimestamp: nr.CreateTimestamp}
+}
+
+type tagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+func (s *server) handleNotes(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	switch r.Method {
+	case http.MethodGet:
+		switch {
+		case q.Has("title"):
+			n, err := s.client.GetByTitle(q.Get("title"))
+			s.writeNote(w, http.StatusOK, n, err)
+		case q.Has("q"):
+			rs, err := s.client.Search(q.Get("q"))
+			s.write(w, http.StatusOK, rs, err)
+		default:
+			ns, err := s.client.ListByTags(q["tag"], q.Get("match") == "all")
+			s.write(w, http.StatusOK, ns, err)
+		}
+	case http.MethodPost:
+		var req noteRequest
+		if err := decode(w, r, &req); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		id, err := s.client.Create(req.note())
+		if err != nil {
+			s.writeError(w, status(err), err)
+			return
+		}
+
+		w.Header().Set("Location", fmt.Sprintf("/notes/%d", id))
+
+		n, err := s.client.GetByID(id)
+		s.writeNote(w, http.StatusCreated, n, err)
+	default:
+		s.methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+func (s *server) handleNote(w http.ResponseWriter, r *http.Request) {
+	id, rest, ok := parseID(r.URL.Path, "/notes/")
+	if !ok {
+		s.writeError(w, http.StatusNotFound, errNoRoute)
+		return
+	}
+
+	switch {
+	case len(rest) == 0:
+		s.handleNoteByID(w, r, id)
+	case len(rest) == 1 && rest[0] == "tags":
+		s.handleTags(w, r, id)
+	case len(rest) == 1 && rest[0] == "used":
+		if r.Method != http.MethodPost {
+			s.methodNotAllowed(w, http.MethodPost)
+			return
+		}
+
+		s.writeNoContent(w, s.client.MarkUsed(id))
+	case rest[0] == "revisions":
+		s.handleRevisions(w, r, id, rest[1:])
+	default:
+		s.writeError(w, http.StatusNotFound, errNoRoute)
+	}
+}
+
+func (s *server) handleNoteByID(w http.ResponseWriter, r *http.Request, id int) {
+	switch r.Method {
+	case http.MethodGet:
+		n, err := s.client.GetByID(id)
+		s.writeNote(w, http.StatusOK, n, err)
+	case http.MethodPatch:
+		var req noteRequest
+		if err := decode(w, r, &req); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if _, err := s.client.Update(id, req.note()); err != nil {
+			s.writeError(w, status(err), err)
+			return
+		}
+
+		n, err := s.client.GetByID(id)
+		s.writeNote(w, http.StatusOK, n, err)
+	case http.MethodDelete:
+		s.writeNoContent(w, s.client.Delete(id))
+	default:
+		s.methodNotAllowed(w, http.MethodGet, http.MethodPatch, http.MethodDelete)
+	}
+}
+
+func (s *server) handleTags(w http.ResponseWriter, r *http.Request, id int) {
+	var err error
+
+	switch r.Method {
+	case http.MethodPost:
+		var req tagsRequest
+		if err := decode(w, r, &req); err != nil {
+			s.writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		err = s.client.Tag(id, req.Tags)
+	case http.MethodDelete:
+		err = s.client.Untag(id, r.URL.Query()["tag"])
+	default:
+		s.methodNotAllowed(w, http.MethodPost, http.MethodDelete)
+		return
+	}
+
+	if err != nil {
+		s.writeError(w, status(err), err)
+		return
+	}
+
+	n, err := s.client.GetByID(id)
+	s.writeNote(w, http.StatusOK, n, err)
+}
+
+func (s *server) handleRevisions(w http.ResponseWriter, r *http.Request, id int, rest []string) {
+	if len(rest) == 0 {
+		if r.Method != http.MethodGet {
+			s.methodNotAllowed(w, http.MethodGet)
+			return
+		}
+
+		rs, err := s.client.Revisions(id)
+		s.write(w, http.StatusOK, rs, err)
+
+		return
+	}
+
+	rev, err := strconv.Atoi(rest[0])
+	if err != nil || len(rest) > 2 || (len(rest) == 2 && rest[1] != "restore") {
+		s.writeError(w, http.StatusNotFound, errNoRoute)
+		return
+	}
+
+	if len(rest) == 1 {
+		if r.Method != http.MethodGet {
+			s.methodNotAllowed(w, http.MethodGet)
+			return
+		}
+
+		rv, err := s.client.Revision(id, rev)
+		s.write(w, http.StatusOK, rv, err)
+
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		s.methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	if _, err := s.client.Restore(id, rev); err != nil {
+		s.writeError(w, status(err), err)
+		return
+	}
+
+	n, err := s.client.GetByID(id)
+	s.writeNote(w, http.StatusOK, n, err)
+}
+
+func (s *server) handleTrash(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		ns, err := s.client.Trash()
+		s.write(w, http.StatusOK, ns, err)
+	case http.MethodDelete:
+		var before time.Time
+
+		if v := r.URL.Query().Get("before"); v != "" {
+			var err error
+
+			before, err = time.Parse(time.RFC3339, v)
+			if err != nil {
+				s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid before: %w", err))
+				return
+			}
+		}
+
+		deleted, err := s.client.EmptyTrash(before)
+		s.write(w, http.StatusOK, map[string]int64{"deleted": deleted}, err)
+	default:
+		s.methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+	}
+}
+
+func (s *server) handleTrashedNote(w http.ResponseWriter, r *http.Request) {
+	id, rest, ok := parseID(r.URL.Path, "/trash/")
+
+	switch {
+	case !ok || len(rest) > 1 || (len(rest) == 1 && rest[0] != "restore"):
+		s.writeError(w, http.StatusNotFound, errNoRoute)
+	case len(rest) == 1 && r.Method == http.MethodPost:
+		s.writeNoContent(w, s.client.Untrash(id))
+	case len(rest) == 1:
+		s.methodNotAllowed(w, http.MethodPost)
+	case r.Method == http.MethodDelete:
+		s.writeNoContent(w, s.client.Purge(id))
+	default:
+		s.methodNotAllowed(w, http.MethodDelete)
+	}
+}
+
+func (s *server) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.methodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	onConflict := notes.ConflictPolicy(r.URL.Query().Get("onConflict"))
+	if onConflict == "" {
+		onConflict = notes.ConflictSkip
+	}
+
+	var ns []notes.Note
+	if err := decode(w, r, &ns); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	res, err := s.client.Import(ns, onConflict)
+	s.write(w, http.StatusOK, res, err)
+}
+
+// parseID splits a path such as /notes/1/tags into the ID and the segments after it.
+func parseID(path, prefix string) (int, []string, bool) {
+	segments := strings.Split(strings.TrimSuffix(strings.TrimPrefix(path, prefix), "/"), "/")
+
+	id, err := strconv.Atoi(segments[0])
+	if err != nil {
+		return 0, nil, false
+	}
+
+	return id, segments[1:], true
+}
+
+// decode decodes the JSON request body into v, rejecting unknown fields.
+func decode(w http.ResponseWriter, r *http.Request, v any) error {
+	d := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	d.DisallowUnknownFields()
+
+	if err := d.Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+
+	return nil
+}
+
+// status returns the status code for an error returned by the notes client.
+func status(err error) int {
+	switch {
+	case errors.Is(err, notes.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, notes.ErrDuplicateTitle):
+		return http.StatusConflict
+	case errors.Is(err, notes.ErrInvalidNote), errors.Is(err, notes.ErrInvalidTag), errors.Is(err, notes.ErrUnknownConflictPolicy):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeNote writes a note, with its placeholders filled in as they are by "get".
+func (s *server) writeNote(w http.ResponseWriter, code int, n *notes.Note, err error) {
+	if err == nil {
+		n.Placeholders = placeholder.Names(n.Description)
+	}
+
+	s.write(w, code, n, err)
+}
+
+// write writes v as JSON with the status code or, if err isn't nil, the error.
+func (s *server) write(w http.ResponseWriter, code int, v any, err error) {
+	if err != nil {
+		s.writeError(w, status(err), err)
+		return
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("unable to encode response: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(append(b, '\n'))
+}
+
+func (s *server) writeNoContent(w http.ResponseWriter, err error) {
+	if err != nil {
+		s.writeError(w, status(err), err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	s.writeError(w, http.StatusMethodNotAllowed, errMethodNotAllowed)
+}
+
+// writeError writes the error as {"error": "message"}. The details of internal errors are
+// logged rather than returned.
+func (s *server) writeError(w http.ResponseWriter, code int, err error) {
+	msg := err.Error()
+
+	if code == http.StatusInternalServerError {
+		if s.log != nil {
+			s.log.Print(err)
+		}
+
+		msg = http.StatusText(code)
+	}
+
+	b, _ := json.Marshal(map[string]string{"error": msg})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(append(b, '\n'))
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+	"github.com/simondrake/copy-paste-notes/internal/notes/notestest"
+)
+
+const token = "secret"
+
+func newServer(t *testing.T) (*httptest.Server, *notes.Client) {
+	t.Helper()
+
+	client := notes.New(notestest.New(
+		notes.Note{Title: "pods", Description: "kubectl get pods -n {{namespace}}", Tags: []string{"k8s"}},
+		notes.Note{Title: "logs", Description: "kubectl logs -f", Tags: []string{"k8s", "debug"}},
+	))
+
+	srv := httptest.NewServer(New(client, token, nil))
+	t.Cleanup(srv.Close)
+
+	return srv, client
+}
+
+// do sends a request with the token, decoding the JSON response into out if it isn't nil.
+func do(t *testing.T, srv *httptest.Server, method, path, body string, out any) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := srv.Client().Do(req)
+	require.NoError(t, err)
+
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	if out != nil {
+		require.NoError(t, json.Unmarshal(b, out), string(b))
+	}
+
+	return res
+}
+
+func TestAuth(t *testing.T) {
+	srv, _ := newServer(t)
+
+	for name, header := range map[string]string{"missing": "", "wrong": "Bearer wrong", "not bearer": "Basic " + token} {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/notes", nil)
+			require.NoError(t, err)
+
+			if header != "" {
+				req.Header.Set("Authorization", header)
+			}
+
+			res, err := srv.Client().Do(req)
+			require.NoError(t, err)
+			res.Body.Close()
+
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+			assert.NotEmpty(t, res.Header.Get("WWW-Authenticate"))
+		})
+	}
+}
+
+func TestNotes(t *testing.T) {
+	srv, client := newServer(t)
+
+	t.Run("list", func(t *testing.T) {
+		var ns []notes.Note
+		res := do(t, srv, http.MethodGet, "/notes?tag=debug", "", &ns)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		require.Len(t, ns, 1)
+		assert.Equal(t, "logs", ns[0].Title)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		var rs []notes.SearchResult
+		res := do(t, srv, http.MethodGet, "/notes?q=pods", "", &rs)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		require.Len(t, rs, 1)
+		assert.Equal(t, "pods", rs[0].Title)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		var n notes.Note
+		res := do(t, srv, http.MethodGet, "/notes/1", "", &n)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "pods", n.Title)
+		assert.Equal(t, []string{"namespace"}, n.Placeholders)
+
+		res = do(t, srv, http.MethodGet, "/notes?title=logs", "", &n)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, 2, n.ID)
+	})
+
+	t.Run("get has the same shape as get --format json", func(t *testing.T) {
+		n, err := client.GetByID(1)
+		require.NoError(t, err)
+
+		n.Placeholders = []string{"namespace"}
+
+		want, err := json.Marshal(n)
+		require.NoError(t, err)
+
+		var got json.RawMessage
+		do(t, srv, http.MethodGet, "/notes/1", "", &got)
+		assert.JSONEq(t, string(want), string(got))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		var e map[string]string
+		res := do(t, srv, http.MethodGet, "/notes/99", "", &e)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+		assert.Equal(t, "no note with id 99", e["error"])
+
+		res = do(t, srv, http.MethodGet, "/notes?title=missing", "", nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		res = do(t, srv, http.MethodGet, "/notes/abc", "", nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		var n notes.Note
+		res := do(t, srv, http.MethodPost, "/notes", `{"title": "events", "description": "kubectl get events", "tags": ["k8s"]}`, &n)
+		assert.Equal(t, http.StatusCreated, res.StatusCode)
+		assert.Equal(t, "/notes/3", res.Header.Get("Location"))
+		assert.Equal(t, 3, n.ID)
+		assert.False(t, n.CreateTimestamp.IsZero())
+	})
+
+	t.Run("create duplicate", func(t *testing.T) {
+		res := do(t, srv, http.MethodPost, "/notes", `{"title": "pods", "description": "again"}`, nil)
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+	})
+
+	t.Run("create invalid", func(t *testing.T) {
+		for _, body := range []string{`{"title": "t"}`, `{"title": "t", "description": "d", "colour": "red"}`, `{"title": `} {
+			res := do(t, srv, http.MethodPost, "/notes", body, nil)
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		var n notes.Note
+		res := do(t, srv, http.MethodPatch, "/notes/2", `{"description": "kubectl logs -f --tail 10"}`, &n)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "logs", n.Title)
+		assert.Equal(t, "kubectl logs -f --tail 10", n.Description)
+		assert.Equal(t, []string{"debug", "k8s"}, n.Tags)
+
+		res = do(t, srv, http.MethodPatch, "/notes/2", `{"title": "pods"}`, nil)
+		assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+		res = do(t, srv, http.MethodPatch, "/notes/99", `{"title": "other"}`, nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		res = do(t, srv, http.MethodPatch, "/notes/2", `{}`, nil)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		var n notes.Note
+		res := do(t, srv, http.MethodPost, "/notes/1/tags", `{"tags": ["pods"]}`, &n)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, []string{"k8s", "pods"}, n.Tags)
+
+		res = do(t, srv, http.MethodDelete, "/notes/1/tags?tag=k8s", "", &n)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, []string{"pods"}, n.Tags)
+
+		res = do(t, srv, http.MethodPost, "/notes/1/tags", `{"tags": ["two words"]}`, nil)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("used", func(t *testing.T) {
+		res := do(t, srv, http.MethodPost, "/notes/1/used", "", nil)
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		var n notes.Note
+		do(t, srv, http.MethodGet, "/notes/1", "", &n)
+		assert.Equal(t, 1, n.UseCount)
+	})
+
+	t.Run("revisions", func(t *testing.T) {
+		var rs []notes.Revision
+		res := do(t, srv, http.MethodGet, "/notes/2/revisions", "", &rs)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		require.Len(t, rs, 1)
+		assert.Equal(t, "kubectl logs -f", rs[0].Description)
+
+		var r notes.Revision
+		res = do(t, srv, http.MethodGet, "/notes/2/revisions/1", "", &r)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, 1, r.Revision)
+
+		res = do(t, srv, http.MethodGet, "/notes/2/revisions/9", "", nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		var n notes.Note
+		res = do(t, srv, http.MethodPost, "/notes/2/revisions/1/restore", "", &n)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "kubectl logs -f", n.Description)
+	})
+
+	t.Run("delete and trash", func(t *testing.T) {
+		res := do(t, srv, http.MethodDelete, "/notes/3", "", nil)
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		res = do(t, srv, http.MethodDelete, "/notes/3", "", nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		var ns []notes.Note
+		do(t, srv, http.MethodGet, "/trash", "", &ns)
+		require.Len(t, ns, 1)
+		assert.Equal(t, "events", ns[0].Title)
+
+		res = do(t, srv, http.MethodPost, "/trash/3/restore", "", nil)
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		do(t, srv, http.MethodDelete, "/notes/3", "", nil)
+
+		res = do(t, srv, http.MethodDelete, "/trash/3", "", nil)
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		do(t, srv, http.MethodDelete, "/notes/2", "", nil)
+
+		var deleted map[string]int64
+		res = do(t, srv, http.MethodDelete, "/trash?before=2000-01-01T00:00:00Z", "", &deleted)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Zero(t, deleted["deleted"])
+
+		res = do(t, srv, http.MethodDelete, "/trash", "", &deleted)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, int64(1), deleted["deleted"])
+
+		res = do(t, srv, http.MethodDelete, "/trash?before=yesterday", "", nil)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("import", func(t *testing.T) {
+		var r notes.ImportResult
+		res := do(t, srv, http.MethodPost, "/import?onConflict=rename", `[{"title": "pods", "description": "copy"}, {"title": "new", "description": "new"}]`, &r)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, notes.ImportResult{Inserted: 1, Renamed: 1}, r)
+
+		res = do(t, srv, http.MethodPost, "/import?onConflict=merge", `[]`, nil)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		res := do(t, srv, http.MethodPut, "/notes/1", "", nil)
+		assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+		assert.Equal(t, "GET, PATCH, DELETE", res.Header.Get("Allow"))
+	})
+
+	t.Run("unknown route", func(t *testing.T) {
+		res := do(t, srv, http.MethodGet, "/notes/1/unknown", "", nil)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
@@ -0,0 +1,21 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+	"github.com/simondrake/copy-paste-notes/internal/notes/notestest"
+)
+
+func TestConformance(t *testing.T) {
+	notestest.Conformance(t, func(t *testing.T) notes.NoteReaderWriter {
+		c, err := New(":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() { c.Close() })
+
+		return c
+	})
+}
@@ -0,0 +1,27 @@
+//go:build cgo && !purego
+
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	driver "github.com/mattn/go-sqlite3"
+)
+
+// driverName is the database/sql driver used to open the database. By default this is
+// github.com/mattn/go-sqlite3, which requires CGO. Full-text search also needs the
+// sqlite_fts5 build tag, without which notes are searched without an index.
+const driverName = "sqlite3"
+
+func newMigrateDriver(db *sql.DB) (database.Driver, error) {
+	return sqlite3.WithInstance(db, &sqlite3.Config{})
+}
+
+func isUniqueConstraint(err error) bool {
+	var se driver.Error
+
+	return errors.As(err, &se) && se.ExtendedCode == driver.ErrConstraintUnique
+}
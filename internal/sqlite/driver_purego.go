@@ -0,0 +1,28 @@
+//go:build purego || !cgo
+
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	driver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// driverName is the database/sql driver used to open the database. With the purego build
+// tag, or when CGO is disabled, this is modernc.org/sqlite, which doesn't need CGO and
+// includes FTS5.
+const driverName = "sqlite"
+
+func newMigrateDriver(db *sql.DB) (database.Driver, error) {
+	return sqlite.WithInstance(db, &sqlite.Config{})
+}
+
+func isUniqueConstraint(err error) bool {
+	var se *driver.Error
+
+	return errors.As(err, &se) && se.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
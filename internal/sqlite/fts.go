@@ -0,0 +1,95 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ftsTriggers keep the full-text search index in step with the notes table.
+var ftsTriggers = []string{"notes_fts_insert", "notes_fts_delete", "notes_fts_update"}
+
+// createFTS creates the full-text search index and its triggers, if they don't exist.
+const createFTS = `
+CREATE VIRTUAL TABLE IF NOT EXISTS "notes_fts" USING fts5(
+  "title",
+  "description",
+  content='notes',
+  content_rowid='id'
+  );
+
+CREATE TRIGGER IF NOT EXISTS "notes_fts_insert" AFTER INSERT ON "notes" BEGIN
+  INSERT INTO "notes_fts" ("rowid", "title", "description") VALUES (new."id", new."title", new."description");
+END;
+
+CREATE TRIGGER IF NOT EXISTS "notes_fts_delete" AFTER DELETE ON "notes" BEGIN
+  INSERT INTO "notes_fts" ("notes_fts", "rowid", "title", "description") VALUES ('delete', old."id", old."title", old."description");
+END;
+
+CREATE TRIGGER IF NOT EXISTS "notes_fts_update" AFTER UPDATE ON "notes" BEGIN
+  INSERT INTO "notes_fts" ("notes_fts", "rowid", "title", "description") VALUES ('delete', old."id", old."title", old."description");
+  INSERT INTO "notes_fts" ("rowid", "title", "description") VALUES (new."id", new."title", new."description");
+END;
+
+INSERT INTO "notes_fts" ("notes_fts") VALUES ('rebuild');
+`
+
+// hasFTS5 returns true if SQLite was built with the FTS5 module, which needs the sqlite_fts5
+// build tag with the default driver.
+func hasFTS5(db *sql.DB) (bool, error) {
+	var used bool
+
+	if err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&used); err != nil {
+		return false, fmt.Errorf("unable to check for FTS5: %w", err)
+	}
+
+	return used, nil
+}
+
+// setupFTS sets up full-text search when SQLite has FTS5, returning whether it's available.
+// The index isn't part of the migrations, so that a binary built without FTS5 can still use
+// the database. Without FTS5 the triggers that maintain the index are dropped, as they would
+// fail, and the index is rebuilt the next time the database is opened with FTS5.
+func setupFTS(db *sql.DB) (bool, error) {
+	available, err := hasFTS5(db)
+	if err != nil {
+		return false, err
+	}
+
+	var triggers int
+
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name IN (?, ?, ?)",
+		ftsTriggers[0], ftsTriggers[1], ftsTriggers[2]).Scan(&triggers); err != nil {
+		return false, fmt.Errorf("unable to check for the search index: %w", err)
+	}
+
+	if !available {
+		for _, t := range ftsTriggers {
+			if _, err := db.Exec(`DROP TRIGGER IF EXISTS "` + t + `"`); err != nil {
+				return false, fmt.Errorf("unable to drop search index trigger: %w", err)
+			}
+		}
+
+		return false, nil
+	}
+
+	if triggers == len(ftsTriggers) {
+		return true, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(createFTS); err != nil {
+		return false, fmt.Errorf("unable to create search index: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+func TestSearchIndex(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cpn.db")
+
+	c, err := New(file)
+	require.NoError(t, err)
+
+	if !c.fts {
+		c.Close()
+		t.Skip("SQLite doesn't have FTS5, so there's no index to rebuild")
+	}
+
+	// Opening the database without FTS5 drops the triggers, so the index misses new notes
+	for _, trigger := range ftsTriggers {
+		_, err := c.db.Exec(`DROP TRIGGER "` + trigger + `"`)
+		require.NoError(t, err)
+	}
+
+	_, err = c.InsertNote(notes.Note{Title: "pods", Description: "kubectl get pods"})
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+
+	c, err = New(file)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { c.Close() })
+
+	rs, err := c.SearchNotes("kubectl")
+	require.NoError(t, err)
+	require.Len(t, rs, 1)
+	assert.Equal(t, "pods", rs[0].Title)
+}
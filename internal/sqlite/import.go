@@ -0,0 +1,143 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+// ErrUnknownConflictPolicy is kept for callers that match on the sqlite package's errors.
+var ErrUnknownConflictPolicy = notes.ErrUnknownConflictPolicy
+
+// ImportNotes inserts the notes in a single transaction, so either all of them are imported
+// or none are. IDs are ignored, notes without a create timestamp are given the current time
+// and the rest of their timestamps and use count are kept. Trashed notes don't count as
+// conflicts, so only a title taken by a live note is handled according to onConflict.
+func (c *Client) ImportNotes(ns []notes.Note, onConflict notes.ConflictPolicy) (notes.ImportResult, error) {
+	var res notes.ImportResult
+
+	if err := onConflict.Validate(); err != nil {
+		return res, err
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return res, err
+	}
+
+	defer tx.Rollback()
+
+	now := notes.Now()
+
+	for _, n := range ns {
+		tags, err := notes.NormaliseTags(n.Tags)
+		if err != nil {
+			return notes.ImportResult{}, fmt.Errorf("note %q: %w", n.Title, err)
+		}
+
+		if n.CreateTimestamp.IsZero() {
+			n.CreateTimestamp = now
+		}
+
+		id, err := titleID(tx, n.Title)
+		if err != nil {
+			return notes.ImportResult{}, err
+		}
+
+		if id != 0 {
+			switch onConflict {
+			case notes.ConflictSkip:
+				res.Skipped++
+				continue
+			case notes.ConflictOverwrite:
+				if err := overwriteNote(tx, id, n, tags); err != nil {
+					return notes.ImportResult{}, err
+				}
+
+				res.Overwritten++
+				continue
+			case notes.ConflictRename:
+				if n.Title, err = freeTitle(tx, n.Title); err != nil {
+					return notes.ImportResult{}, err
+				}
+
+				res.Renamed++
+			}
+		} else {
+			res.Inserted++
+		}
+
+		r, err := tx.Exec(
+			"INSERT INTO notes (create_timestamp, title, description, updated_at, last_used_at, use_count) VALUES (?,?,?,?,?,?)",
+			timestamp(n.CreateTimestamp), n.Title, n.Description, timestamp(n.UpdateTimestamp), timestamp(n.LastUsedTimestamp), n.UseCount,
+		)
+		if err != nil {
+			return notes.ImportResult{}, err
+		}
+
+		newID, err := r.LastInsertId()
+		if err != nil {
+			return notes.ImportResult{}, err
+		}
+
+		if err := addTags(tx, int(newID), tags); err != nil {
+			return notes.ImportResult{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return notes.ImportResult{}, err
+	}
+
+	return res, nil
+}
+
+// titleID returns the ID of the note with the given title that isn't in the trash, or 0 if
+// there isn't one.
+func titleID(tx *sql.Tx, title string) (int, error) {
+	var id int
+
+	err := tx.QueryRow("SELECT id FROM notes WHERE title = ? AND deleted_at IS NULL", title).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	return id, err
+}
+
+// freeTitle returns the title with the lowest numbered suffix, starting at " (2)", that
+// isn't taken.
+func freeTitle(tx *sql.Tx, title string) (string, error) {
+	for i := 2; ; i++ {
+		t := fmt.Sprintf("%s (%d)", title, i)
+
+		id, err := titleID(tx, t)
+		if err != nil {
+			return "", err
+		}
+
+		if id == 0 {
+			return t, nil
+		}
+	}
+}
+
+// overwriteNote replaces a note with an imported one, including its last used timestamp and
+// use count, saving the previous version as a revision.
+func overwriteNote(tx *sql.Tx, id int, n notes.Note, tags []string) error {
+	if err := saveRevision(tx, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE notes SET create_timestamp = ?, description = ?, updated_at = ?, last_used_at = ?, use_count = ? WHERE id = ?",
+		timestamp(n.CreateTimestamp), n.Description, notes.FormatTimestamp(time.Now()), timestamp(n.LastUsedTimestamp), n.UseCount, id,
+	); err != nil {
+		return err
+	}
+
+	return setTags(tx, id, tags)
+}
@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+
+	"github.com/simondrake/copy-paste-notes/internal/migrations"
+)
+
+var ErrDirtySchema = errors.New("the database schema is dirty, a previous migration failed. Fix the schema and run `migrate force <version>`")
+
+// NewMigrate opens the database in the given file, without applying any pending migrations,
+// and returns a migrate instance for managing its schema. Closing it closes the database.
+func NewMigrate(file string) (*migrate.Migrate, error) {
+	db, err := sql.Open(driverName, file)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := newMigrate(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func newMigrate(db *sql.DB) (*migrate.Migrate, error) {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	drv, err := newMigrateDriver(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return migrate.NewWithInstance("iofs", src, driverName, drv)
+}
+
+// migrateUp applies any pending migrations to the database.
+func migrateUp(db *sql.DB) error {
+	m, err := newMigrate(db)
+	if err != nil {
+		return err
+	}
+
+	// m isn't closed, as that would close db
+
+	_, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+
+	if dirty {
+		return ErrDirtySchema
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("unable to apply migrations: %w", err)
+	}
+
+	return nil
+}
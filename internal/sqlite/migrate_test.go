@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateBaselineSchema(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cpn.db")
+
+	// Before migrations were applied automatically, New created the notes table itself
+	// without recording a schema version
+	db, err := sql.Open(driverName, file)
+	require.NoError(t, err)
+
+	_, err = db.Exec("CREATE TABLE IF NOT EXISTS [notes] ( id INTEGER NOT NULL PRIMARY KEY, create_timestamp TEXT, title TEXT NOT NULL UNIQUE, description TEXT NOT NULL);")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO notes (create_timestamp, title, description) VALUES ('2023-09-01 10:00:00', 'pods', 'kubectl get pods')")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	c, err := New(file)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { c.Close() })
+
+	n, err := c.GetNoteByTitle("pods")
+	require.NoError(t, err)
+	assert.Equal(t, "kubectl get pods", n.Description)
+	assert.Empty(t, n.Tags)
+
+	rs, err := c.SearchNotes("pods")
+	require.NoError(t, err)
+	assert.Len(t, rs, 1)
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+// saveRevision copies the current title and description of a note into the next revision,
+// unless the note is in the trash.
+func saveRevision(tx *sql.Tx, id int) error {
+	_, err := tx.Exec(`INSERT INTO note_revisions (note_id, revision, create_timestamp, title, description)
+		SELECT id, COALESCE((SELECT MAX(revision) FROM note_revisions WHERE note_id = notes.id), 0) + 1, ?, title, description
+		FROM notes WHERE id = ? AND deleted_at IS NULL`, notes.FormatTimestamp(time.Now()), id)
+
+	return err
+}
+
+func (c *Client) ListNoteRevisions(id int) ([]notes.Revision, error) {
+	rows, err := c.db.Query("SELECT note_id, revision, create_timestamp, title, description FROM note_revisions WHERE note_id = ? ORDER BY revision", id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	out := make([]notes.Revision, 0)
+	for rows.Next() {
+		r, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *r)
+	}
+
+	return out, rows.Err()
+}
+
+func (c *Client) GetNoteRevision(id int, revision int) (*notes.Revision, error) {
+	return scanRevision(c.db.QueryRow("SELECT note_id, revision, create_timestamp, title, description FROM note_revisions WHERE note_id = ? AND revision = ?", id, revision))
+}
+
+func scanRevision(s scanner) (*notes.Revision, error) {
+	r := &notes.Revision{}
+
+	var created sql.NullString
+
+	if err := s.Scan(&r.NoteID, &r.Revision, &created, &r.Title, &r.Description); err != nil {
+		return nil, err
+	}
+
+	t, err := parseTimestamp(created)
+	if err != nil {
+		return nil, err
+	}
+
+	r.CreateTimestamp = t
+
+	return r, nil
+}
+
+// RestoreNoteRevision sets the title and description of a note back to those of the given
+// revision. The version being replaced is itself saved as a new revision.
+func (c *Client) RestoreNoteRevision(id int, revision int) (int64, error) {
+	r, err := c.GetNoteRevision(id, revision)
+	if err != nil {
+		return 0, err
+	}
+
+	return c.UpdateNote(id, notes.Note{Title: r.Title, Description: r.Description})
+}
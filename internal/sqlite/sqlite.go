@@ -3,40 +3,122 @@ package sqlite
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
-
-	_ "github.com/mattn/go-sqlite3"
+	"time"
 
 	"github.com/simondrake/copy-paste-notes/internal/notes"
 )
 
-var ErrDeleteFailed = errors.New("delete failed")
+var (
+	ErrDeleteFailed  = fmt.Errorf("delete failed: %w", notes.ErrNotFound)
+	ErrEmptyQuery    = errors.New("search query must not be empty")
+	ErrRestoreFailed = fmt.Errorf("restore failed: %w", notes.ErrNotFound)
+)
 
 type Client struct {
 	db *sql.DB
+	// fts is true if SQLite has FTS5, otherwise notes are searched without the index
+	fts bool
 }
 
+// New opens the database in the given file, applying any pending migrations. The file may be
+// ":memory:" for a database that only lasts as long as the client.
 func New(file string) (*Client, error) {
-	db, err := sql.Open("sqlite3", file)
+	db, err := sql.Open(driverName, file)
 	if err != nil {
 		return nil, err
 	}
 
-	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS [notes] ( id INTEGER NOT NULL PRIMARY KEY, create_timestamp TEXT, title TEXT NOT NULL UNIQUE, description TEXT NOT NULL);"); err != nil {
+	if file == ":memory:" {
+		// Every connection to :memory: gets its own database, so only ever use one
+		db.SetMaxOpenConns(1)
+	}
+
+	if err := migrateUp(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	fts, err := setupFTS(db)
+	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
 	return &Client{
-		db: db,
+		db:  db,
+		fts: fts,
 	}, nil
 }
 
+// Close closes the database.
+func (c *Client) Close() error {
+	return c.db.Close()
+}
+
+// noteColumns are the columns selected for a note, in the order expected by scanNote.
+const noteColumns = "notes.id, notes.create_timestamp, notes.title, notes.description, notes.deleted_at, notes.updated_at, notes.last_used_at, notes.use_count, " + tagsColumn
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanNote(s scanner, dest ...any) (*notes.Note, error) {
+	n := &notes.Note{}
+
+	var created, deleted, updated, lastUsed, tags sql.NullString
+
+	if err := s.Scan(append([]any{&n.ID, &created, &n.Title, &n.Description, &deleted, &updated, &lastUsed, &n.UseCount, &tags}, dest...)...); err != nil {
+		return nil, err
+	}
+
+	for _, ts := range []struct {
+		s    sql.NullString
+		dest *time.Time
+	}{
+		{created, &n.CreateTimestamp},
+		{deleted, &n.DeleteTimestamp},
+		{updated, &n.UpdateTimestamp},
+		{lastUsed, &n.LastUsedTimestamp},
+	} {
+		t, err := parseTimestamp(ts.s)
+		if err != nil {
+			return nil, fmt.Errorf("note %d: %w", n.ID, err)
+		}
+
+		*ts.dest = t
+	}
+
+	n.Tags = splitTags(tags)
+
+	return n, nil
+}
+
+// timestamp returns the value stored for t, which is NULL if it is zero.
+func timestamp(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+
+	return notes.FormatTimestamp(t)
+}
+
+// parseTimestamp parses a stored timestamp, returning the zero time for NULL.
+func parseTimestamp(s sql.NullString) (time.Time, error) {
+	if !s.Valid || s.String == "" {
+		return time.Time{}, nil
+	}
+
+	return notes.ParseTimestamp(s.String)
+}
+
 func (c *Client) Ping() error {
 	return c.db.Ping()
 }
 
 func (c *Client) ListNotes() ([]notes.Note, error) {
-	rows, err := c.db.Query("SELECT id, create_timestamp, title, description FROM notes")
+	rows, err := c.db.Query("SELECT " + noteColumns + " FROM notes WHERE deleted_at IS NULL")
 	if err != nil {
 		return nil, err
 	}
@@ -45,54 +127,125 @@ func (c *Client) ListNotes() ([]notes.Note, error) {
 
 	out := make([]notes.Note, 0)
 	for rows.Next() {
-		n := notes.Note{}
-		if err := rows.Scan(&n.ID, &n.CreateTimestamp, &n.Title, &n.Description); err != nil {
+		n, err := scanNote(rows)
+		if err != nil {
 			return nil, err
 		}
-		out = append(out, n)
+		out = append(out, *n)
 	}
 
 	return out, nil
 }
 
-func (c *Client) GetNoteByID(id int) (*notes.Note, error) {
-	row := c.db.QueryRow("SELECT id, create_timestamp, title, description FROM notes WHERE id=?", id)
+// ftsQuery turns free text into an FTS5 query, quoting each term so that characters such as
+// '-' or ':' (common in command snippets) aren't treated as query syntax. Every term is
+// prefix matched and all terms must be present.
+func ftsQuery(query string) string {
+	terms := strings.Fields(query)
 
-	n := &notes.Note{}
-
-	if err := row.Scan(&n.ID, &n.CreateTimestamp, &n.Title, &n.Description); err != nil {
-		return nil, err
+	out := make([]string, len(terms))
+	for i, t := range terms {
+		out[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"*`
 	}
 
-	return n, nil
+	return strings.Join(out, " ")
 }
 
-func (c *Client) GetNoteByTitle(title string) (*notes.Note, error) {
-	row := c.db.QueryRow("SELECT id, create_timestamp, title, description FROM notes WHERE title=?", title)
+func (c *Client) SearchNotes(query string) ([]notes.SearchResult, error) {
+	q := ftsQuery(query)
+	if q == "" {
+		return nil, ErrEmptyQuery
+	}
 
-	n := &notes.Note{}
+	if !c.fts {
+		ns, err := c.ListNotes()
+		if err != nil {
+			return nil, err
+		}
+
+		return notes.MatchNotes(ns, query), nil
+	}
 
-	if err := row.Scan(&n.ID, &n.CreateTimestamp, &n.Title, &n.Description); err != nil {
+	rows, err := c.db.Query(`SELECT `+noteColumns+`,
+		highlight(notes_fts, 0, ?, ?), snippet(notes_fts, 1, ?, ?, '...', 16), bm25(notes_fts, 10.0, 1.0)
+		FROM notes_fts JOIN notes ON notes.id = notes_fts.rowid
+		WHERE notes_fts MATCH ? AND notes.deleted_at IS NULL
+		ORDER BY bm25(notes_fts, 10.0, 1.0)`,
+		notes.HighlightStart, notes.HighlightEnd, notes.HighlightStart, notes.HighlightEnd, q)
+	if err != nil {
 		return nil, err
 	}
 
-	return n, nil
+	defer rows.Close()
+
+	out := make([]notes.SearchResult, 0)
+	for rows.Next() {
+		r := notes.SearchResult{}
+
+		n, err := scanNote(rows, &r.TitleHighlight, &r.DescriptionHighlight, &r.Rank)
+		if err != nil {
+			return nil, err
+		}
+
+		r.Note = *n
+		out = append(out, r)
+	}
+
+	return out, rows.Err()
+}
+
+func (c *Client) GetNoteByID(id int) (*notes.Note, error) {
+	return scanNote(c.db.QueryRow("SELECT "+noteColumns+" FROM notes WHERE id=? AND deleted_at IS NULL", id))
+}
+
+func (c *Client) GetNoteByTitle(title string) (*notes.Note, error) {
+	return scanNote(c.db.QueryRow("SELECT "+noteColumns+" FROM notes WHERE title=? AND deleted_at IS NULL", title))
 }
 
 func (c *Client) InsertNote(n notes.Note) (int, error) {
-	res, err := c.db.Exec("INSERT INTO notes VALUES(NULL,?,?,?);", n.CreateTimestamp, n.Title, n.Description)
+	tags, err := notes.NormaliseTags(n.Tags)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := c.db.Begin()
 	if err != nil {
 		return 0, err
 	}
 
+	defer tx.Rollback()
+
+	res, err := tx.Exec("INSERT INTO notes (create_timestamp, title, description) VALUES (?,?,?);", timestamp(n.CreateTimestamp), n.Title, n.Description)
+	if err != nil {
+		return 0, duplicateTitle(err)
+	}
+
 	id, err := res.LastInsertId()
 	if err != nil {
 		return 0, err
 	}
 
+	if err := addTags(tx, int(id), tags); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
 	return int(id), nil
 }
 
+// duplicateTitle wraps errors caused by the unique constraint on the title with
+// notes.ErrDuplicateTitle.
+func duplicateTitle(err error) error {
+	if isUniqueConstraint(err) {
+		return fmt.Errorf("%w: %v", notes.ErrDuplicateTitle, err)
+	}
+
+	return err
+}
+
 func appendStatement(stmt string, field string) string {
 	if strings.HasSuffix(strings.TrimSpace(stmt), "?") {
 		return stmt + ", " + field + " = ?"
@@ -102,50 +255,98 @@ func appendStatement(stmt string, field string) string {
 }
 
 func (c *Client) UpdateNote(id int, note notes.Note) (int64, error) {
-	if note.Title == "" && note.Description == "" {
+	if note.Title == "" && note.Description == "" && note.Tags == nil {
 		return 0, errors.New("at least one field to update must be provided")
 	}
 
-	stmtStr := "UPDATE notes SET"
-
-	args := make([]interface{}, 0)
-
-	if note.Title != "" {
-		stmtStr = appendStatement(stmtStr, "title")
-		args = append(args, note.Title)
-	}
-
-	if note.Description != "" {
-		stmtStr = appendStatement(stmtStr, "description")
-		args = append(args, note.Description)
+	tags, err := notes.NormaliseTags(note.Tags)
+	if err != nil {
+		return 0, err
 	}
 
-	stmtStr = stmtStr + " WHERE id = ?"
-
-	stmt, err := c.db.Prepare(stmtStr)
+	tx, err := c.db.Begin()
 	if err != nil {
 		return 0, err
 	}
 
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	args = append(args, id)
+	var affected int64
 
-	res, err := stmt.Exec(args...)
-	if err != nil {
-		return 0, err
+	if note.Title != "" || note.Description != "" {
+		if err := saveRevision(tx, id); err != nil {
+			return 0, err
+		}
+
+		stmtStr := "UPDATE notes SET"
+
+		args := make([]interface{}, 0)
+
+		if note.Title != "" {
+			stmtStr = appendStatement(stmtStr, "title")
+			args = append(args, note.Title)
+		}
+
+		if note.Description != "" {
+			stmtStr = appendStatement(stmtStr, "description")
+			args = append(args, note.Description)
+		}
+
+		stmtStr = appendStatement(stmtStr, "updated_at")
+		args = append(args, notes.FormatTimestamp(time.Now()))
+
+		stmtStr = stmtStr + " WHERE id = ? AND deleted_at IS NULL"
+
+		stmt, err := tx.Prepare(stmtStr)
+		if err != nil {
+			return 0, err
+		}
+
+		defer stmt.Close()
+
+		args = append(args, id)
+
+		res, err := stmt.Exec(args...)
+		if err != nil {
+			return 0, duplicateTitle(err)
+		}
+
+		affected, err = res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		// Nothing was updated, so the deferred rollback discards the revision
+		if affected == 0 {
+			return 0, nil
+		}
+	} else {
+		// Only the tags are being updated, so report whether the note exists
+		if err := tx.QueryRow("SELECT COUNT(*) FROM notes WHERE id = ? AND deleted_at IS NULL", id).Scan(&affected); err != nil {
+			return 0, err
+		}
 	}
 
-	affected, err := res.RowsAffected()
-	if err != nil {
+	if note.Tags != nil && affected > 0 {
+		if err := setTags(tx, id, tags); err != nil {
+			return 0, err
+		}
+
+		if err := touch(tx, id); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
 		return 0, err
 	}
 
 	return affected, nil
 }
 
+// DeleteNote moves a note to the trash. Use PurgeNote to delete it permanently.
 func (c *Client) DeleteNote(id int) error {
-	res, err := c.db.Exec("DELETE FROM notes WHERE id=?", id)
+	res, err := c.db.Exec("UPDATE notes SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", notes.FormatTimestamp(time.Now()), id)
 	if err != nil {
 		return err
 	}
@@ -161,3 +362,28 @@ func (c *Client) DeleteNote(id int) error {
 
 	return nil
 }
+
+// touch sets the time a note was last updated to now.
+func touch(tx *sql.Tx, id int) error {
+	_, err := tx.Exec("UPDATE notes SET updated_at = ? WHERE id = ?", notes.FormatTimestamp(time.Now()), id)
+	return err
+}
+
+// MarkNoteUsed sets the time a note was last used to now and increments its use count.
+func (c *Client) MarkNoteUsed(id int) error {
+	res, err := c.db.Exec("UPDATE notes SET last_used_at = ?, use_count = use_count + 1 WHERE id = ? AND deleted_at IS NULL", notes.FormatTimestamp(time.Now()), id)
+	if err != nil {
+		return err
+	}
+
+	ra, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if ra == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
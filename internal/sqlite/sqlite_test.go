@@ -98,7 +98,7 @@ func TestListNotes(t *testing.T) {
 	note := notes.Note{
 		Title:           "test-list-title",
 		Description:     "test-list-description",
-		CreateTimestamp: time.Now().Format("2006-01-02 15:04:05"),
+		CreateTimestamp: notes.Now(),
 	}
 
 	var rid int
@@ -138,7 +138,7 @@ func TestGetNoteByID(t *testing.T) {
 	note := notes.Note{
 		Title:           "test-getbyID-title",
 		Description:     "test-getbyID-description",
-		CreateTimestamp: time.Now().Format("2006-01-02 15:04:05"),
+		CreateTimestamp: notes.Now(),
 	}
 
 	var rid int
@@ -177,7 +177,7 @@ func TestGetNoteByTitle(t *testing.T) {
 	note := notes.Note{
 		Title:           "test-getbyTitle-title",
 		Description:     "test-getbyTitle-description",
-		CreateTimestamp: time.Now().Format("2006-01-02 15:04:05"),
+		CreateTimestamp: notes.Now(),
 	}
 
 	var rid int
@@ -209,7 +209,7 @@ func TestInsertNote(t *testing.T) {
 	note := notes.Note{
 		Title:           "test-insert-title",
 		Description:     "test-insert-description",
-		CreateTimestamp: time.Now().Format("2006-01-02 15:04:05"),
+		CreateTimestamp: notes.Now(),
 	}
 
 	var rid int
@@ -235,7 +235,7 @@ func TestUpdateNote(t *testing.T) {
 	note := notes.Note{
 		Title:           "test-update-title",
 		Description:     "test-update-description",
-		CreateTimestamp: time.Now().Format("2006-01-02 15:04:05"),
+		CreateTimestamp: notes.Now(),
 	}
 
 	var rid int
@@ -307,7 +307,7 @@ func TestDeleteNote(t *testing.T) {
 	note := notes.Note{
 		Title:           "test-delete-title",
 		Description:     "test-delete-description",
-		CreateTimestamp: time.Now().Format("2006-01-02 15:04:05"),
+		CreateTimestamp: notes.Now(),
 	}
 
 	var rid int
@@ -329,6 +329,497 @@ func TestDeleteNote(t *testing.T) {
 	})
 }
 
+func TestSearchNotes(t *testing.T) {
+	t.Run("should return an error when the query is empty", func(t *testing.T) {
+		rs, err := client.SearchNotes("   ")
+		assert.Nil(t, rs)
+		assert.ErrorIs(t, err, ErrEmptyQuery)
+	})
+
+	first := notes.Note{
+		Title:           "kubectl logs",
+		Description:     "kubectl -n default logs my-pod",
+		CreateTimestamp: notes.Now(),
+	}
+
+	second := notes.Note{
+		Title:           "psql connect",
+		Description:     "psql -h localhost -U postgres, like kubectl for databases",
+		CreateTimestamp: notes.Now(),
+	}
+
+	var firstID, secondID int
+
+	t.Run("should insert notes without error", func(t *testing.T) {
+		var err error
+
+		firstID, err = client.InsertNote(first)
+		require.NoError(t, err)
+
+		secondID, err = client.InsertNote(second)
+		require.NoError(t, err)
+	})
+
+	t.Run("should rank title matches first", func(t *testing.T) {
+		rs, err := client.SearchNotes("kubectl")
+		assert.NoError(t, err)
+		require.Len(t, rs, 2)
+
+		assert.Equal(t, firstID, rs[0].ID)
+		assert.Equal(t, secondID, rs[1].ID)
+		assert.Equal(t, notes.HighlightStart+"kubectl"+notes.HighlightEnd+" logs", rs[0].TitleHighlight)
+		assert.Contains(t, rs[1].DescriptionHighlight, notes.HighlightStart+"kubectl"+notes.HighlightEnd)
+	})
+
+	t.Run("should prefix match and treat query syntax literally", func(t *testing.T) {
+		rs, err := client.SearchNotes("-n defa")
+		assert.NoError(t, err)
+		require.Len(t, rs, 1)
+		assert.Equal(t, firstID, rs[0].ID)
+	})
+
+	t.Run("should reflect updates to the note", func(t *testing.T) {
+		_, err := client.UpdateNote(secondID, notes.Note{Description: "psql -h localhost -U postgres"})
+		require.NoError(t, err)
+
+		rs, err := client.SearchNotes("kubectl")
+		assert.NoError(t, err)
+		require.Len(t, rs, 1)
+		assert.Equal(t, firstID, rs[0].ID)
+	})
+
+	t.Run("should delete the notes successfully", func(t *testing.T) {
+		require.NoError(t, client.DeleteNote(firstID))
+		require.NoError(t, client.DeleteNote(secondID))
+
+		rs, err := client.SearchNotes("psql")
+		assert.NoError(t, err)
+		assert.Zero(t, len(rs))
+	})
+}
+
+func TestTags(t *testing.T) {
+	t.Run("should return an error when tagging a note that does not exist", func(t *testing.T) {
+		err := client.TagNote(9009, []string{"k8s"})
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	note := notes.Note{
+		Title:           "test-tags-title",
+		Description:     "test-tags-description",
+		CreateTimestamp: notes.Now(),
+		Tags:            []string{"kubectl", " k8s ", "kubectl"},
+	}
+
+	var rid int
+
+	t.Run("should reject invalid tags", func(t *testing.T) {
+		_, err := client.InsertNote(notes.Note{Title: "invalid", Description: "invalid", Tags: []string{"two words"}})
+		assert.ErrorIs(t, err, ErrInvalidTag)
+
+		ns, err := client.ListNotes()
+		assert.NoError(t, err)
+		assert.Zero(t, len(ns))
+	})
+
+	t.Run("should insert note with normalised tags", func(t *testing.T) {
+		var err error
+
+		rid, err = client.InsertNote(note)
+		require.NoError(t, err)
+
+		n, err := client.GetNoteByID(rid)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"k8s", "kubectl"}, n.Tags)
+	})
+
+	t.Run("should add and remove tags", func(t *testing.T) {
+		require.NoError(t, client.TagNote(rid, []string{"logs", "k8s"}))
+
+		n, err := client.GetNoteByTitle(note.Title)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"k8s", "kubectl", "logs"}, n.Tags)
+
+		require.NoError(t, client.UntagNote(rid, []string{"kubectl", "does-not-exist"}))
+
+		ns, err := client.ListNotes()
+		assert.NoError(t, err)
+		require.Len(t, ns, 1)
+		assert.Equal(t, []string{"k8s", "logs"}, ns[0].Tags)
+	})
+
+	t.Run("should replace tags on update and leave them alone otherwise", func(t *testing.T) {
+		_, err := client.UpdateNote(rid, notes.Note{Description: "something else"})
+		require.NoError(t, err)
+
+		n, err := client.GetNoteByID(rid)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"k8s", "logs"}, n.Tags)
+
+		ra, err := client.UpdateNote(rid, notes.Note{Tags: []string{"psql"}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), ra)
+
+		n, err = client.GetNoteByID(rid)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"psql"}, n.Tags)
+
+		ra, err = client.UpdateNote(rid, notes.Note{Tags: []string{}})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), ra)
+
+		n, err = client.GetNoteByID(rid)
+		assert.NoError(t, err)
+		assert.Nil(t, n.Tags)
+	})
+
+	t.Run("should delete the note successfully", func(t *testing.T) {
+		require.NoError(t, client.TagNote(rid, []string{"orphan"}))
+		require.NoError(t, client.PurgeNote(rid))
+
+		var count int
+		require.NoError(t, client.db.QueryRow("SELECT COUNT(*) FROM tags").Scan(&count))
+		assert.Zero(t, count)
+	})
+}
+
+func TestRevisions(t *testing.T) {
+	t.Run("should return an error when the revision does not exist", func(t *testing.T) {
+		r, err := client.GetNoteRevision(9009, 1)
+		assert.Nil(t, r)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	note := notes.Note{
+		Title:           "test-revisions-title",
+		Description:     "test-revisions-description",
+		CreateTimestamp: notes.Now(),
+	}
+
+	var rid int
+
+	t.Run("should insert note without any revisions", func(t *testing.T) {
+		var err error
+
+		rid, err = client.InsertNote(note)
+		require.NoError(t, err)
+
+		rs, err := client.ListNoteRevisions(rid)
+		assert.NoError(t, err)
+		assert.Zero(t, len(rs))
+	})
+
+	t.Run("should save the previous version on update", func(t *testing.T) {
+		_, err := client.UpdateNote(rid, notes.Note{Description: "second-description"})
+		require.NoError(t, err)
+
+		_, err = client.UpdateNote(rid, notes.Note{Title: "second-title"})
+		require.NoError(t, err)
+
+		// Updating only the tags doesn't create a revision
+		_, err = client.UpdateNote(rid, notes.Note{Tags: []string{"tag"}})
+		require.NoError(t, err)
+
+		rs, err := client.ListNoteRevisions(rid)
+		assert.NoError(t, err)
+		require.Len(t, rs, 2)
+
+		assert.Equal(t, 1, rs[0].Revision)
+		assert.Equal(t, note.Title, rs[0].Title)
+		assert.Equal(t, note.Description, rs[0].Description)
+
+		assert.Equal(t, 2, rs[1].Revision)
+		assert.Equal(t, note.Title, rs[1].Title)
+		assert.Equal(t, "second-description", rs[1].Description)
+	})
+
+	t.Run("should restore a revision", func(t *testing.T) {
+		ra, err := client.RestoreNoteRevision(rid, 1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), ra)
+
+		n, err := client.GetNoteByID(rid)
+		assert.NoError(t, err)
+		assert.Equal(t, note.Title, n.Title)
+		assert.Equal(t, note.Description, n.Description)
+
+		r, err := client.GetNoteRevision(rid, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, "second-title", r.Title)
+		assert.Equal(t, "second-description", r.Description)
+	})
+
+	t.Run("should purge the note and its revisions successfully", func(t *testing.T) {
+		require.NoError(t, client.PurgeNote(rid))
+
+		rs, err := client.ListNoteRevisions(rid)
+		assert.NoError(t, err)
+		assert.Zero(t, len(rs))
+	})
+}
+
+func TestTrash(t *testing.T) {
+	t.Run("should return an error when restoring a note that is not in the trash", func(t *testing.T) {
+		err := client.RestoreNote(9009)
+		assert.ErrorIs(t, err, ErrRestoreFailed)
+	})
+
+	t.Run("should return an error when purging a note that does not exist", func(t *testing.T) {
+		err := client.PurgeNote(9009)
+		assert.ErrorIs(t, err, ErrDeleteFailed)
+	})
+
+	// Clear out the notes trashed by the other tests
+	_, err := client.EmptyTrash(time.Time{})
+	require.NoError(t, err)
+
+	note := notes.Note{
+		Title:           "test-trash-title",
+		Description:     "test-trash-description",
+		CreateTimestamp: notes.Now(),
+	}
+
+	var rid int
+
+	t.Run("should insert note without error", func(t *testing.T) {
+		var err error
+
+		rid, err = client.InsertNote(note)
+		require.NoError(t, err)
+	})
+
+	t.Run("should hide the note once deleted", func(t *testing.T) {
+		require.NoError(t, client.DeleteNote(rid))
+		assert.ErrorIs(t, client.DeleteNote(rid), ErrDeleteFailed)
+
+		_, err := client.GetNoteByID(rid)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+
+		_, err = client.GetNoteByTitle(note.Title)
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+
+		ra, err := client.UpdateNote(rid, notes.Note{Title: "trashed"})
+		assert.NoError(t, err)
+		assert.Zero(t, ra)
+
+		ns, err := client.ListNotes()
+		assert.NoError(t, err)
+		assert.Zero(t, len(ns))
+
+		ns, err = client.ListTrashedNotes()
+		assert.NoError(t, err)
+		require.Len(t, ns, 1)
+		assert.Equal(t, rid, ns[0].ID)
+		assert.NotEmpty(t, ns[0].DeleteTimestamp)
+	})
+
+	t.Run("should restore the note", func(t *testing.T) {
+		require.NoError(t, client.RestoreNote(rid))
+
+		n, err := client.GetNoteByID(rid)
+		assert.NoError(t, err)
+		assert.Empty(t, n.DeleteTimestamp)
+
+		ns, err := client.ListTrashedNotes()
+		assert.NoError(t, err)
+		assert.Zero(t, len(ns))
+	})
+
+	t.Run("should only empty notes trashed before the given time", func(t *testing.T) {
+		require.NoError(t, client.DeleteNote(rid))
+
+		deleted, err := client.EmptyTrash(time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Zero(t, deleted)
+
+		deleted, err = client.EmptyTrash(time.Time{})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+
+		ns, err := client.ListTrashedNotes()
+		assert.NoError(t, err)
+		assert.Zero(t, len(ns))
+	})
+}
+
+func TestImportNotes(t *testing.T) {
+	t.Run("should return an error for an unknown conflict policy", func(t *testing.T) {
+		_, err := client.ImportNotes(nil, "merge")
+		assert.ErrorIs(t, err, ErrUnknownConflictPolicy)
+	})
+
+	existing := notes.Note{
+		Title:           "test-import-title",
+		Description:     "test-import-description",
+		CreateTimestamp: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Tags:            []string{"old"},
+	}
+
+	var eid int
+
+	t.Run("should insert note without error", func(t *testing.T) {
+		var err error
+
+		eid, err = client.InsertNote(existing)
+		require.NoError(t, err)
+	})
+
+	imported := []notes.Note{
+		{Title: existing.Title, Description: "imported-description", CreateTimestamp: time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC), Tags: []string{"new"}},
+		{Title: "test-import-other", Description: "other-description", CreateTimestamp: time.Date(2022, 6, 2, 12, 0, 0, 0, time.UTC)},
+	}
+
+	t.Run("should leave the database untouched when a note is invalid", func(t *testing.T) {
+		_, err := client.ImportNotes(append(imported, notes.Note{Title: "bad", Description: "bad", Tags: []string{"a b"}}), notes.ConflictSkip)
+		assert.ErrorIs(t, err, ErrInvalidTag)
+
+		_, err = client.GetNoteByTitle("test-import-other")
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+	})
+
+	t.Run("should skip conflicting notes", func(t *testing.T) {
+		res, err := client.ImportNotes(imported, notes.ConflictSkip)
+		require.NoError(t, err)
+		assert.Equal(t, notes.ImportResult{Inserted: 1, Skipped: 1}, res)
+
+		n, err := client.GetNoteByTitle("test-import-other")
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2022, 6, 2, 12, 0, 0, 0, time.UTC), n.CreateTimestamp)
+		require.NoError(t, client.PurgeNote(n.ID))
+
+		n, err = client.GetNoteByID(eid)
+		require.NoError(t, err)
+		assert.Equal(t, existing.Description, n.Description)
+	})
+
+	t.Run("should rename conflicting notes", func(t *testing.T) {
+		res, err := client.ImportNotes(imported[:1], notes.ConflictRename)
+		require.NoError(t, err)
+		assert.Equal(t, notes.ImportResult{Renamed: 1}, res)
+
+		n, err := client.GetNoteByTitle(existing.Title + " (2)")
+		require.NoError(t, err)
+		assert.Equal(t, "imported-description", n.Description)
+		assert.Equal(t, []string{"new"}, n.Tags)
+
+		require.NoError(t, client.PurgeNote(n.ID))
+	})
+
+	t.Run("should overwrite conflicting notes", func(t *testing.T) {
+		res, err := client.ImportNotes(imported[:1], notes.ConflictOverwrite)
+		require.NoError(t, err)
+		assert.Equal(t, notes.ImportResult{Overwritten: 1}, res)
+
+		n, err := client.GetNoteByID(eid)
+		require.NoError(t, err)
+		assert.Equal(t, "imported-description", n.Description)
+		assert.Equal(t, time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC), n.CreateTimestamp)
+		assert.Equal(t, []string{"new"}, n.Tags)
+
+		rs, err := client.ListNoteRevisions(eid)
+		assert.NoError(t, err)
+		require.Len(t, rs, 1)
+		assert.Equal(t, existing.Description, rs[0].Description)
+	})
+
+	t.Run("should not conflict with notes in the trash", func(t *testing.T) {
+		require.NoError(t, client.DeleteNote(eid))
+
+		res, err := client.ImportNotes(imported[:1], notes.ConflictSkip)
+		require.NoError(t, err)
+		assert.Equal(t, notes.ImportResult{Inserted: 1}, res)
+
+		n, err := client.GetNoteByTitle(existing.Title)
+		require.NoError(t, err)
+		assert.NotEqual(t, eid, n.ID)
+		require.NoError(t, client.PurgeNote(n.ID))
+	})
+
+	t.Run("should purge the note successfully", func(t *testing.T) {
+		require.NoError(t, client.PurgeNote(eid))
+	})
+}
+
+func TestDuplicateTitle(t *testing.T) {
+	note := notes.Note{
+		Title:           "test-duplicate-title",
+		Description:     "test-duplicate-description",
+		CreateTimestamp: notes.Now(),
+	}
+
+	id, err := client.InsertNote(note)
+	require.NoError(t, err)
+
+	other, err := client.InsertNote(notes.Note{Title: "test-duplicate-other", Description: "d"})
+	require.NoError(t, err)
+
+	t.Run("should return ErrDuplicateTitle when inserting a taken title", func(t *testing.T) {
+		_, err := client.InsertNote(note)
+		assert.ErrorIs(t, err, notes.ErrDuplicateTitle)
+	})
+
+	t.Run("should return ErrDuplicateTitle when updating to a taken title", func(t *testing.T) {
+		_, err := client.UpdateNote(other, notes.Note{Title: note.Title})
+		assert.ErrorIs(t, err, notes.ErrDuplicateTitle)
+	})
+
+	t.Run("should purge the notes successfully", func(t *testing.T) {
+		require.NoError(t, client.PurgeNote(id))
+		require.NoError(t, client.PurgeNote(other))
+		assert.ErrorIs(t, client.PurgeNote(id), notes.ErrNotFound)
+	})
+}
+
+func TestTimestampMigration(t *testing.T) {
+	file := path.Join(t.TempDir(), "legacy.db")
+
+	m, err := NewMigrate(file)
+	require.NoError(t, err)
+	require.NoError(t, m.Migrate(5))
+
+	db, err := sql.Open(driverName, file)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO notes (id, create_timestamp, title, description, deleted_at) VALUES
+		(1, '2023-09-01 10:00:00', 'live', 'd', NULL),
+		(2, '2023-09-02 10:00:00', 'deleted', 'd', '2023-09-03 10:00:00')`)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`INSERT INTO note_revisions (note_id, revision, create_timestamp, title, description) VALUES
+		(1, 1, '2023-09-01 12:00:00', 'live', 'old')`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	require.NoError(t, m.Up())
+	m.Close()
+
+	c, err := New(file)
+	require.NoError(t, err)
+
+	defer c.Close()
+
+	local := func(day, hour int) time.Time {
+		return time.Date(2023, 9, day, hour, 0, 0, 0, time.Local).UTC()
+	}
+
+	n, err := c.GetNoteByID(1)
+	require.NoError(t, err)
+	assert.Equal(t, local(1, 10), n.CreateTimestamp)
+	assert.Equal(t, local(1, 12), n.UpdateTimestamp, "the last revision is when the note was updated")
+	assert.True(t, n.LastUsedTimestamp.IsZero())
+
+	rs, err := c.ListNoteRevisions(1)
+	require.NoError(t, err)
+	require.Len(t, rs, 1)
+	assert.Equal(t, local(1, 12), rs[0].CreateTimestamp)
+
+	trashed, err := c.ListTrashedNotes()
+	require.NoError(t, err)
+	require.Len(t, trashed, 1)
+	assert.Equal(t, local(3, 10), trashed[0].DeleteTimestamp)
+	assert.True(t, trashed[0].UpdateTimestamp.IsZero())
+}
+
 func TestAppendStatement(t *testing.T) {
 	stmt := "UPDATE notes SET"
 
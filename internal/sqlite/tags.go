@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+// ErrInvalidTag is kept for callers that match on the sqlite package's errors.
+var ErrInvalidTag = notes.ErrInvalidTag
+
+// tagsColumn selects a comma separated list of the tags for the note in the current row.
+const tagsColumn = "(SELECT group_concat(tags.name, ',') FROM note_tags JOIN tags ON tags.id = note_tags.tag_id WHERE note_tags.note_id = notes.id)"
+
+func splitTags(s sql.NullString) []string {
+	if !s.Valid || s.String == "" {
+		return nil
+	}
+
+	tags := strings.Split(s.String, ",")
+	sort.Strings(tags)
+
+	return tags
+}
+
+func noteExists(tx *sql.Tx, id int) error {
+	var found int
+	return tx.QueryRow("SELECT 1 FROM notes WHERE id = ? AND deleted_at IS NULL", id).Scan(&found)
+}
+
+func addTags(tx *sql.Tx, id int, tags []string) error {
+	for _, t := range tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", t); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("INSERT OR IGNORE INTO note_tags (note_id, tag_id) SELECT ?, id FROM tags WHERE name = ?", id, t); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func removeTags(tx *sql.Tx, id int, tags []string) error {
+	for _, t := range tags {
+		if _, err := tx.Exec("DELETE FROM note_tags WHERE note_id = ? AND tag_id IN (SELECT id FROM tags WHERE name = ?)", id, t); err != nil {
+			return err
+		}
+	}
+
+	return pruneTags(tx)
+}
+
+func setTags(tx *sql.Tx, id int, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM note_tags WHERE note_id = ?", id); err != nil {
+		return err
+	}
+
+	if err := addTags(tx, id, tags); err != nil {
+		return err
+	}
+
+	return pruneTags(tx)
+}
+
+// pruneTags removes any tags that are no longer attached to a note.
+func pruneTags(tx *sql.Tx) error {
+	_, err := tx.Exec("DELETE FROM tags WHERE id NOT IN (SELECT tag_id FROM note_tags)")
+	return err
+}
+
+func (c *Client) TagNote(id int, tags []string) error {
+	return c.changeTags(id, tags, addTags)
+}
+
+func (c *Client) UntagNote(id int, tags []string) error {
+	return c.changeTags(id, tags, removeTags)
+}
+
+func (c *Client) changeTags(id int, tags []string, change func(*sql.Tx, int, []string) error) error {
+	tags, err := notes.NormaliseTags(tags)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := noteExists(tx, id); err != nil {
+		return err
+	}
+
+	if err := change(tx, id, tags); err != nil {
+		return err
+	}
+
+	if err := touch(tx, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
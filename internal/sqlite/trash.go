@@ -0,0 +1,122 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+func (c *Client) ListTrashedNotes() ([]notes.Note, error) {
+	rows, err := c.db.Query("SELECT " + noteColumns + " FROM notes WHERE deleted_at IS NOT NULL ORDER BY deleted_at")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	out := make([]notes.Note, 0)
+	for rows.Next() {
+		n, err := scanNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *n)
+	}
+
+	return out, rows.Err()
+}
+
+// RestoreNote takes a note out of the trash, which fails with notes.ErrDuplicateTitle if
+// another note has taken its title since.
+func (c *Client) RestoreNote(id int) error {
+	res, err := c.db.Exec("UPDATE notes SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return duplicateTitle(err)
+	}
+
+	ra, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if ra == 0 {
+		return ErrRestoreFailed
+	}
+
+	return nil
+}
+
+// PurgeNote permanently deletes a note, whether or not it is in the trash, along with its
+// tags and revisions.
+func (c *Client) PurgeNote(id int) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	ra, err := purge(tx, "id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	if ra == 0 {
+		return ErrDeleteFailed
+	}
+
+	return tx.Commit()
+}
+
+// EmptyTrash permanently deletes the notes that were moved to the trash before the given
+// time, or all of them if it is zero. It returns the number of notes deleted.
+func (c *Client) EmptyTrash(before time.Time) (int64, error) {
+	where, args := "deleted_at IS NOT NULL", []any{}
+
+	if !before.IsZero() {
+		where, args = where+" AND deleted_at < ?", append(args, notes.FormatTimestamp(before))
+	}
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	ra, err := purge(tx, where, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return ra, nil
+}
+
+// purge deletes the notes matching the where clause, and everything that references them.
+func purge(tx *sql.Tx, where string, args ...any) (int64, error) {
+	for _, stmt := range []string{
+		"DELETE FROM note_tags WHERE note_id IN (SELECT id FROM notes WHERE " + where + ")",
+		"DELETE FROM note_revisions WHERE note_id IN (SELECT id FROM notes WHERE " + where + ")",
+	} {
+		if _, err := tx.Exec(stmt, args...); err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := tx.Exec("DELETE FROM notes WHERE "+where, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	ra, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return ra, pruneTags(tx)
+}
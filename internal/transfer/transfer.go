@@ -0,0 +1,295 @@
+// Package transfer encodes and decodes notes for exporting and importing them.
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+type Format string
+
+const (
+	FormatJSON   Format = "json"
+	FormatNDJSON Format = "ndjson"
+	FormatYAML   Format = "yaml"
+	FormatCSV    Format = "csv"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown format")
+	ErrInvalidNote   = errors.New("invalid note")
+)
+
+// csvHeader is the header row of a CSV export. Tags are separated by spaces, as they can't
+// contain whitespace.
+var csvHeader = []string{"id", "title", "description", "createTimestamp", "updateTimestamp", "lastUsedTimestamp", "useCount", "tags"}
+
+// record is the exported form of a note. Timestamps are RFC3339, although timestamps in the
+// legacy format of older exports are accepted on import.
+type record struct {
+	ID                int      `json:"id,omitempty" yaml:"id,omitempty"`
+	Title             string   `json:"title" yaml:"title"`
+	Description       string   `json:"description" yaml:"description"`
+	CreateTimestamp   string   `json:"createTimestamp,omitempty" yaml:"createTimestamp,omitempty"`
+	UpdateTimestamp   string   `json:"updateTimestamp,omitempty" yaml:"updateTimestamp,omitempty"`
+	LastUsedTimestamp string   `json:"lastUsedTimestamp,omitempty" yaml:"lastUsedTimestamp,omitempty"`
+	UseCount          int      `json:"useCount,omitempty" yaml:"useCount,omitempty"`
+	Tags              []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// ParseFormat returns the format with the given name or, if it is empty, the format matching
+// the extension of the file name, defaulting to JSON.
+func ParseFormat(name string, file string) (Format, error) {
+	if name == "" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".ndjson", ".jsonl":
+			return FormatNDJSON, nil
+		case ".yaml", ".yml":
+			return FormatYAML, nil
+		case ".csv":
+			return FormatCSV, nil
+		default:
+			return FormatJSON, nil
+		}
+	}
+
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatJSON, FormatNDJSON, FormatYAML, FormatCSV:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, name)
+	}
+}
+
+// Encode writes the notes to w in the given format.
+func Encode(w io.Writer, f Format, ns []notes.Note) error {
+	rs := make([]record, len(ns))
+	for i, n := range ns {
+		rs[i] = record{
+			ID:                n.ID,
+			Title:             n.Title,
+			Description:       n.Description,
+			CreateTimestamp:   formatTimestamp(n.CreateTimestamp),
+			UpdateTimestamp:   formatTimestamp(n.UpdateTimestamp),
+			LastUsedTimestamp: formatTimestamp(n.LastUsedTimestamp),
+			UseCount:          n.UseCount,
+			Tags:              n.Tags,
+		}
+	}
+
+	switch f {
+	case FormatJSON:
+		e := json.NewEncoder(w)
+		e.SetEscapeHTML(false)
+		e.SetIndent("", "    ")
+		return e.Encode(rs)
+	case FormatNDJSON:
+		e := json.NewEncoder(w)
+		e.SetEscapeHTML(false)
+		for _, r := range rs {
+			if err := e.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatYAML:
+		e := yaml.NewEncoder(w)
+		if err := e.Encode(rs); err != nil {
+			return err
+		}
+		return e.Close()
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, r := range rs {
+			useCount := ""
+			if r.UseCount != 0 {
+				useCount = strconv.Itoa(r.UseCount)
+			}
+
+			if err := cw.Write([]string{strconv.Itoa(r.ID), r.Title, r.Description, r.CreateTimestamp, r.UpdateTimestamp, r.LastUsedTimestamp, useCount, strings.Join(r.Tags, " ")}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+	}
+}
+
+// Decode reads notes in the given format from r. Every note must have a title and a
+// description, and unknown fields are rejected, so that a malformed file is caught before
+// anything is imported.
+func Decode(r io.Reader, f Format) ([]notes.Note, error) {
+	var (
+		rs  []record
+		err error
+	)
+
+	switch f {
+	case FormatJSON:
+		d := json.NewDecoder(r)
+		d.DisallowUnknownFields()
+		err = d.Decode(&rs)
+	case FormatNDJSON:
+		rs, err = decodeNDJSON(r)
+	case FormatYAML:
+		d := yaml.NewDecoder(r)
+		d.KnownFields(true)
+		if err = d.Decode(&rs); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	case FormatCSV:
+		rs, err = decodeCSV(r)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]notes.Note, len(rs))
+	for i, r := range rs {
+		if strings.TrimSpace(r.Title) == "" || strings.TrimSpace(r.Description) == "" {
+			return nil, fmt.Errorf("%w: note %d must have a title and description", ErrInvalidNote, i+1)
+		}
+
+		if r.UseCount < 0 {
+			return nil, fmt.Errorf("%w: note %d has a negative useCount", ErrInvalidNote, i+1)
+		}
+
+		out[i] = notes.Note{ID: r.ID, Title: r.Title, Description: r.Description, UseCount: r.UseCount, Tags: r.Tags}
+
+		timestamps := []struct {
+			name  string
+			value string
+			dst   *time.Time
+		}{
+			{"createTimestamp", r.CreateTimestamp, &out[i].CreateTimestamp},
+			{"updateTimestamp", r.UpdateTimestamp, &out[i].UpdateTimestamp},
+			{"lastUsedTimestamp", r.LastUsedTimestamp, &out[i].LastUsedTimestamp},
+		}
+
+		for _, ts := range timestamps {
+			if ts.value == "" {
+				continue
+			}
+
+			if *ts.dst, err = notes.ParseTimestamp(ts.value); err != nil {
+				return nil, fmt.Errorf("%w: note %d has an invalid %s: %v", ErrInvalidNote, i+1, ts.name, err)
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func decodeNDJSON(r io.Reader) ([]record, error) {
+	rs := make([]record, 0)
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for line := 1; s.Scan(); line++ {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+
+		d := json.NewDecoder(strings.NewReader(s.Text()))
+		d.DisallowUnknownFields()
+
+		var rec record
+		if err := d.Decode(&rec); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		rs = append(rs, rec)
+	}
+
+	return rs, s.Err()
+}
+
+func decodeCSV(r io.Reader) ([]record, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return []record{}, nil
+	}
+
+	columns := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		switch name {
+		case "id", "title", "description", "createTimestamp", "updateTimestamp", "lastUsedTimestamp", "useCount", "tags":
+			columns[name] = i
+		default:
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+	}
+
+	get := func(row []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return row[i]
+		}
+
+		return ""
+	}
+
+	rs := make([]record, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		rec := record{
+			Title:             get(row, "title"),
+			Description:       get(row, "description"),
+			CreateTimestamp:   get(row, "createTimestamp"),
+			UpdateTimestamp:   get(row, "updateTimestamp"),
+			LastUsedTimestamp: get(row, "lastUsedTimestamp"),
+		}
+
+		if tags := strings.Fields(get(row, "tags")); len(tags) > 0 {
+			rec.Tags = tags
+		}
+
+		if id := get(row, "id"); id != "" {
+			if rec.ID, err = strconv.Atoi(id); err != nil {
+				return nil, fmt.Errorf("row %d: invalid id %q", i+2, id)
+			}
+		}
+
+		if n := get(row, "useCount"); n != "" {
+			if rec.UseCount, err = strconv.Atoi(n); err != nil {
+				return nil, fmt.Errorf("row %d: invalid useCount %q", i+2, n)
+			}
+		}
+
+		rs = append(rs, rec)
+	}
+
+	return rs, nil
+}
+
+// formatTimestamp returns the exported form of t, which is empty if it is zero.
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return notes.FormatTimestamp(t)
+}
@@ -0,0 +1,88 @@
+package transfer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/simondrake/copy-paste-notes/internal/notes"
+)
+
+func TestRoundTrip(t *testing.T) {
+	ns := []notes.Note{
+		{
+			ID:                1,
+			Title:             "kubectl logs",
+			Description:       "kubectl -n <namespace> logs {{pod}}",
+			CreateTimestamp:   time.Date(2023, 9, 1, 10, 0, 0, 0, time.UTC),
+			UpdateTimestamp:   time.Date(2023, 9, 3, 8, 15, 0, 0, time.UTC),
+			LastUsedTimestamp: time.Date(2023, 9, 4, 17, 45, 30, 0, time.UTC),
+			UseCount:          7,
+			Tags:              []string{"k8s", "logs"},
+		},
+		{ID: 2, Title: "quotes, \"commas\"", Description: "line one\nline two", CreateTimestamp: time.Date(2023, 9, 2, 11, 30, 0, 0, time.UTC)},
+	}
+
+	for _, f := range []Format{FormatJSON, FormatNDJSON, FormatYAML, FormatCSV} {
+		t.Run(string(f), func(t *testing.T) {
+			var b bytes.Buffer
+
+			require.NoError(t, Encode(&b, f, ns))
+
+			got, err := Decode(&b, f)
+			require.NoError(t, err)
+			assert.Equal(t, ns, got)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+		input  string
+	}{
+		{name: "malformed json", format: FormatJSON, input: `[{"title": "t", "description": "d"`},
+		{name: "unknown json field", format: FormatJSON, input: `[{"title": "t", "description": "d", "colour": "red"}]`},
+		{name: "malformed ndjson line", format: FormatNDJSON, input: "{\"title\": \"t\", \"description\": \"d\"}\n{\"title\": "},
+		{name: "unknown yaml field", format: FormatYAML, input: "- title: t\n  description: d\n  colour: red\n"},
+		{name: "unknown csv column", format: FormatCSV, input: "title,description,colour\nt,d,red\n"},
+		{name: "missing description", format: FormatJSON, input: `[{"title": "t"}]`},
+		{name: "invalid timestamp", format: FormatJSON, input: `[{"title": "t", "description": "d", "createTimestamp": "yesterday"}]`},
+		{name: "invalid last used timestamp", format: FormatYAML, input: "- title: t\n  description: d\n  lastUsedTimestamp: yesterday\n"},
+		{name: "negative use count", format: FormatJSON, input: `[{"title": "t", "description": "d", "useCount": -1}]`},
+		{name: "invalid csv use count", format: FormatCSV, input: "title,description,useCount\nt,d,often\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run("should return an error for "+tt.name, func(t *testing.T) {
+			ns, err := Decode(strings.NewReader(tt.input), tt.format)
+			assert.Nil(t, ns)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDecodeLegacyTimestamp(t *testing.T) {
+	ns, err := Decode(strings.NewReader("title,description,createTimestamp\nt,d,2023-09-01 10:00:00\n"), FormatCSV)
+	require.NoError(t, err)
+	require.Len(t, ns, 1)
+	assert.Equal(t, time.Date(2023, 9, 1, 10, 0, 0, 0, time.Local).UTC(), ns[0].CreateTimestamp)
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("", "notes.yml")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatYAML, f)
+
+	f, err = ParseFormat("CSV", "notes.json")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatCSV, f)
+
+	_, err = ParseFormat("xml", "")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}